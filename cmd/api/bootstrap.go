@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"go-template-microservice/internal/config"
 	"go-template-microservice/internal/constants"
 	"go-template-microservice/internal/handlers"
@@ -13,6 +15,7 @@ import (
 	"go-template-microservice/pkg/sqlite"
 	"go-template-microservice/pkg/utils"
 	"go-template-microservice/pkg/validator"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2/middleware/recover"
@@ -61,22 +64,162 @@ func (b *bootstrap) registerMiddlewares(app *fiber.App) {
 	app.Use(middleware.ValidationMiddleware(b.validator))
 }
 
-func CreateRouter(
+// components are the long living parts of the application main needs to run and shut down
+type components struct {
+	router         router.IRouter
+	scheduler      services.MessageScheduler
+	messageService services.MessageService
+}
+
+// CreateComponents wires the application, ctx is the root context of the background work
+// and cancelling it aborts any in-flight webhook sends
+func CreateComponents(
+	ctx context.Context,
 	db sqlite.ISqliteInstance,
 	redis redis.IRedisInstance,
 	cfg config.IConfig,
 	l *logrus.Logger,
-) router.IRouter {
+) *components {
 	messageRepository := repository.NewMessageRepository(db, l)
 	messageCacheRepository := repository.NewMessageCacheRepository(
 		redis,
 		time.Duration(cfg.Redis().TTLInSeconds)*time.Second,
 		l,
 	)
-	messageSender := services.NewMessageSenderService(cfg.WebhookConfig().Url, cfg.WebhookConfig().AuthKey, l)
-	messageScheduler := services.NewMessageScheduler(messageRepository, messageSender, messageCacheRepository, time.Duration(cfg.Scheduler().IntervalInSeconds)*time.Second, cfg.Scheduler().BatchSize, l)
-	messageService := services.NewMessageService(messageRepository, messageCacheRepository, messageScheduler, l)
+	messageSender, err := newMessageSender(ctx, cfg.WebhookConfig(), l)
+	if err != nil {
+		l.Fatalf("Invalid delivery provider configuration: %v", err)
+	}
+	rateLimited := cfg.WebhookConfig().RateLimitPerSecond > 0
+	if rateLimited {
+		messageSender = services.NewRateLimitedSender(messageSender, cfg.WebhookConfig().RateLimitPerSecond, cfg.WebhookConfig().RateLimitBurst, l)
+	}
+	// the breaker wraps the rate limiter so refused sends don't wait for a token
+	var circuitBreaker *services.CircuitBreaker
+	if cfg.WebhookConfig().CircuitBreakerThreshold > 0 {
+		circuitBreaker = services.NewCircuitBreaker(services.CircuitBreakerOptions{
+			FailureThreshold: cfg.WebhookConfig().CircuitBreakerThreshold,
+			CoolDown:         time.Duration(cfg.WebhookConfig().CircuitBreakerCoolDownInSeconds) * time.Second,
+		}, l)
+		messageSender = services.NewCircuitBreakerSender(messageSender, circuitBreaker, l)
+	}
+	sendingSchedule, err := services.ParseSendingSchedule(strings.Split(cfg.Scheduler().SendingWindows, ";"), cfg.Scheduler().SendingTimezone)
+	if err != nil {
+		l.Fatalf("Invalid sending windows: %v", err)
+	}
+	exemptCategories := make([]string, 0, len(cfg.Scheduler().WindowExemptCategories))
+	for _, category := range cfg.Scheduler().WindowExemptCategories {
+		if category = strings.ToLower(strings.TrimSpace(category)); category != "" {
+			exemptCategories = append(exemptCategories, category)
+		}
+	}
+	instanceID := utils.GetInstanceID()
+	messageScheduler := services.NewMessageScheduler(ctx, messageRepository, messageSender, messageCacheRepository, services.SchedulerOptions{
+		Interval:         time.Duration(cfg.Scheduler().IntervalInSeconds) * time.Second,
+		BatchSize:        cfg.Scheduler().BatchSize,
+		MaxAttempts:      cfg.Scheduler().MaxAttempts,
+		BackoffBase:      time.Duration(cfg.Scheduler().BackoffBaseInSeconds) * time.Second,
+		BackoffMax:       time.Duration(cfg.Scheduler().BackoffMaxInSeconds) * time.Second,
+		WorkerID:         instanceID,
+		LeaseDuration:    time.Duration(cfg.Scheduler().LeaseInSeconds) * time.Second,
+		SendTimeout:      services.SendTimeout,
+		Concurrency:      cfg.Scheduler().Concurrency,
+		Drain:            rateLimited,
+		RatePerSecond:    cfg.WebhookConfig().RateLimitPerSecond,
+		LowPriorityShare: cfg.Scheduler().LowPrioritySharePercent,
+		SendingSchedule:  sendingSchedule,
+		ExemptCategories: exemptCategories,
+		CircuitBreaker:   circuitBreaker,
+	}, l)
+	schedulerStateRepository := repository.NewSchedulerStateRepository(redis, l)
+	if cfg.Scheduler().LeaderElection {
+		messageScheduler, err = services.NewLeaderElectedScheduler(ctx, messageScheduler, repository.NewLeaderLeaseRepository(redis, l), schedulerStateRepository, services.LeaderElectionOptions{
+			Identity:      instanceID,
+			LeaseDuration: time.Duration(cfg.Scheduler().LeaderLeaseInSeconds) * time.Second,
+		}, l)
+		if err != nil {
+			l.Fatalf("Invalid leader election configuration: %v", err)
+		}
+	}
+	messageService := services.NewMessageService(messageRepository, messageCacheRepository, messageScheduler, schedulerStateRepository, l)
 
 	messageHandler := handlers.NewMessageHandler(messageService, l)
-	return router.NewRouter(messageHandler, l)
+	return &components{
+		router:         router.NewRouter(messageHandler, circuitBreaker, l),
+		scheduler:      messageScheduler,
+		messageService: messageService,
+	}
+}
+
+// newMessageSender registers the configured delivery providers and routes every message to one of them
+func newMessageSender(ctx context.Context, cfg config.WebhookConfig, l *logrus.Logger) (services.MessageSenderService, error) {
+	auth, err := newWebhookAuth(cfg, l)
+	if err != nil {
+		return nil, err
+	}
+	webhookOptions := []services.WebhookOption{services.WithAuth(auth)}
+	if len(cfg.SigningSecrets) > 0 {
+		signer, err := services.NewWebhookSigner(cfg.SignatureHeader, cfg.SigningSecrets)
+		if err != nil {
+			return nil, err
+		}
+		webhookOptions = append(webhookOptions, services.WithSigner(signer))
+	}
+
+	registry := services.NewProviderRegistry()
+	endpoints, err := services.ParseWebhookEndpoints(cfg.Endpoints)
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) > 0 {
+		registry.Register("json", services.NewFailoverSender(ctx, endpoints, func(url string) services.MessageSenderService {
+			return services.NewMessageSenderService(url, cfg.AuthKey, l, webhookOptions...)
+		}, services.FailoverOptions{
+			FailureThreshold: cfg.EndpointFailureThreshold,
+			ProbeInterval:    time.Duration(cfg.EndpointProbeIntervalInSeconds) * time.Second,
+		}, l))
+	} else {
+		registry.Register("json", services.NewMessageSenderService(cfg.Url, cfg.AuthKey, l, webhookOptions...))
+	}
+	if cfg.FormUrl != "" {
+		registry.Register("form", services.NewFormWebhookSender(cfg.FormUrl, cfg.AuthKey, l, webhookOptions...))
+	}
+	if cfg.SmtpAddr != "" {
+		registry.Register("smtp", services.NewSMTPSender(services.SMTPSenderOptions{
+			Addr:     cfg.SmtpAddr,
+			Username: cfg.SmtpUsername,
+			Password: cfg.SmtpPassword,
+			From:     cfg.SmtpFrom,
+			Domain:   cfg.SmtpDomain,
+		}, l))
+	}
+
+	rules, err := services.ParseRoutingRules(cfg.Routes)
+	if err != nil {
+		return nil, err
+	}
+	return services.NewRoutingSender(registry, cfg.Provider, rules, l)
+}
+
+// newWebhookAuth builds the authentication strategy of the webhook requests
+func newWebhookAuth(cfg config.WebhookConfig, l *logrus.Logger) (services.WebhookAuth, error) {
+	switch strings.ToLower(cfg.AuthType) {
+	case "", "header":
+		return services.NewStaticHeaderAuth(cfg.AuthHeader, cfg.AuthKey), nil
+	case "basic":
+		return services.NewBasicAuth(cfg.BasicUsername, cfg.BasicPassword), nil
+	case "oauth2":
+		if cfg.TokenUrl == "" {
+			return nil, errors.New("oauth2 webhook authentication requires a token url")
+		}
+		return services.NewOAuth2ClientCredentials(services.OAuth2Options{
+			TokenURL:      cfg.TokenUrl,
+			ClientID:      cfg.ClientId,
+			ClientSecret:  cfg.ClientSecret,
+			Scopes:        cfg.TokenScopes,
+			RefreshBefore: time.Duration(cfg.TokenRefreshBeforeInSeconds) * time.Second,
+		}, l), nil
+	default:
+		return nil, fmt.Errorf("unknown webhook auth type %q, expected header, basic or oauth2", cfg.AuthType)
+	}
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"go-template-microservice/internal/config"
 	"go-template-microservice/internal/models"
 	"go-template-microservice/pkg/redis"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -20,7 +22,7 @@ func main() {
 	logger.Info("Application Starting")
 
 	// Initialize database with schema
-	db, err := sqlite.NewSqliteInstanceWithSchemas(config.Database().Name, []string{
+	db, err := sqlite.NewSqliteInstanceWithSchemas(config.Database().Name, []sqlite.Schema{
 		models.GetMessageSchema(),
 	})
 
@@ -36,7 +38,10 @@ func main() {
 	}
 	defer redis.Close()
 
-	router := CreateRouter(db, redis, config, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	components := CreateComponents(ctx, db, redis, config, logger)
 
 	app := bootstrapApplication(&bootstrap{
 		logger:    logger,
@@ -44,7 +49,10 @@ func main() {
 		configs:   config,
 	})
 
-	router.RegisterRoutes(app)
+	components.router.RegisterRoutes(app)
+
+	// resume the scheduler if operators left it running, or start it when configured to
+	components.messageService.RestoreScheduler(ctx, config.Scheduler().AutoStart)
 
 	go func() {
 		if err := app.Listen(":" + config.Server().HttpPort); err != nil {
@@ -57,6 +65,14 @@ func main() {
 	<-c
 
 	logger.Info("Info: Application Gracefully Shutting Down")
+
+	// let the in-flight sends finish before the server and its connections go away,
+	// sends still running after the shutdown timeout are cancelled and requeued
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), time.Duration(config.Server().ShutdownTimeout)*time.Second)
+	defer cancelShutdown()
+	components.scheduler.Stop(shutdownCtx)
+	cancel()
+
 	if gShoutDown := app.Shutdown(); gShoutDown != nil {
 		logger.Error(gShoutDown)
 	}
@@ -19,6 +19,341 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/messages": {
+            "get": {
+                "description": "Lists messages newest first with filters and keyset cursor pagination. Pass the next_cursor of a page as cursor to fetch the following one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "List Messages",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "PENDING",
+                                "SENDING",
+                                "SENT",
+                                "FAILED",
+                                "EXPIRED",
+                                "CANCELLED"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Statuses to include, repeat the parameter for several",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Recipient",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Substring of the content",
+                        "name": "content",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after, RFC 3339",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before, RFC 3339",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sent at or after, RFC 3339",
+                        "name": "sent_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sent before, RFC 3339",
+                        "name": "sent_to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "sent_at"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Field to sort and paginate by",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page to fetch",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Maximum number of messages to retrieve",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_internal_resources_response.MessagesPaginationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPValidationErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Enqueues a new message with PENDING status to be delivered by the scheduler",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Create Message",
+                "parameters": [
+                    {
+                        "description": "Message to enqueue",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_internal_resources_request.CreateMessageRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_internal_resources_response.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPValidationErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/batch": {
+            "post": {
+                "description": "Enqueues up to 1000 messages in a single transaction. Invalid items are rejected individually and reported in the per-item results",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Create Messages In Bulk",
+                "parameters": [
+                    {
+                        "description": "Messages to enqueue",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_internal_resources_request.CreateMessagesBatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_internal_resources_response.BatchMessagesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPValidationErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/dispatch": {
+            "post": {
+                "description": "Runs one scheduler tick synchronously, whether or not the scheduler is running, and returns how many messages were sent, retried, failed and skipped. A tick of the running scheduler in progress is waited for first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Dispatch Messages",
+                "parameters": [
+                    {
+                        "description": "Optional batch size override",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_internal_resources_request.DispatchMessagesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_internal_resources_response.DispatchSummaryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPValidationErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/scheduler": {
+            "get": {
+                "description": "Returns whether the scheduler is running, its tick timings and the number of messages sent, retried and failed in the last tick and since start",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Get Message Scheduler Status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_internal_resources_response.SchedulerStatusResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Changes the interval, batch size and concurrency of the scheduler without a restart. Omitted fields keep their value, a tick in progress finishes with the previous settings",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Update Message Scheduler",
+                "parameters": [
+                    {
+                        "description": "Settings to change",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_internal_resources_request.UpdateSchedulerRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_internal_resources_response.SchedulerStatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPValidationErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/messages/sent": {
             "get": {
                 "description": "Retrieves a list of sent messages",
@@ -114,43 +449,650 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "go-template-microservice_internal_resources_response.SentMessageResponse": {
-            "type": "object",
-            "properties": {
-                "content": {
-                    "type": "string"
-                },
-                "external_message_id": {
-                    "type": "string"
-                },
-                "message_id": {
-                    "type": "integer"
-                },
-                "sent_at": {
-                    "type": "string"
-                },
-                "to": {
-                    "type": "string"
-                }
-            }
         },
-        "go-template-microservice_internal_resources_response.SentMessagesResponse": {
-            "type": "object",
-            "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/go-template-microservice_internal_resources_response.SentMessageResponse"
-                    }
-                },
-                "status": {
-                    "type": "string"
-                },
-                "timestamp": {
-                    "type": "integer"
+        "/messages/{id}": {
+            "get": {
+                "description": "Retrieves a single message by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Get Message",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_internal_resources_response.MessageResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Moves a PENDING message to CANCELLED so it is never sent. Messages being sent or already in a final status are refused. When updated_at is given the cancellation is refused if the message was modified since",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Cancel Message",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The updated_at last read, RFC 3339",
+                        "name": "updated_at",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_internal_resources_response.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Edits the recipient or content of a PENDING message. When updated_at is given the update is refused if the message was modified since",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Update Message",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_internal_resources_request.UpdateMessageRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_internal_resources_response.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-template-microservice_pkg_utils.HTTPErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "go-template-microservice_internal_models.Message": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "external_message_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "lease_expires_at": {
+                    "type": "string"
+                },
+                "lease_owner": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "priority": {
+                    "$ref": "#/definitions/go-template-microservice_internal_models.Priority"
+                },
+                "scheduled_at": {
+                    "type": "string"
+                },
+                "sent_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/go-template-microservice_internal_models.Status"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "go-template-microservice_internal_models.Priority": {
+            "type": "string",
+            "enum": [
+                "high",
+                "normal",
+                "low"
+            ],
+            "x-enum-varnames": [
+                "PriorityHigh",
+                "PriorityNormal",
+                "PriorityLow"
+            ]
+        },
+        "go-template-microservice_internal_models.Status": {
+            "type": "string",
+            "enum": [
+                "PENDING",
+                "SENDING",
+                "SENT",
+                "FAILED",
+                "EXPIRED",
+                "CANCELLED"
+            ],
+            "x-enum-varnames": [
+                "StatusPending",
+                "StatusSending",
+                "StatusSent",
+                "StatusFailed",
+                "StatusExpired",
+                "StatusCancelled"
+            ]
+        },
+        "go-template-microservice_internal_resources_request.CreateMessageRequest": {
+            "type": "object",
+            "required": [
+                "content",
+                "to"
+            ],
+            "properties": {
+                "category": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "example": "otp"
+                },
+                "content": {
+                    "type": "string",
+                    "maxLength": 160
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "string",
+                    "default": "normal",
+                    "enum": [
+                        "high",
+                        "normal",
+                        "low"
+                    ]
+                },
+                "scheduled_at": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string",
+                    "maxLength": 20
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_request.CreateMessagesBatchRequest": {
+            "type": "object",
+            "required": [
+                "messages"
+            ],
+            "properties": {
+                "messages": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/go-template-microservice_internal_resources_request.CreateMessageRequest"
+                    }
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_request.DispatchMessagesRequest": {
+            "type": "object",
+            "properties": {
+                "batch_size": {
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 1
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_request.UpdateMessageRequest": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string",
+                    "maxLength": 160,
+                    "minLength": 1
+                },
+                "to": {
+                    "type": "string",
+                    "maxLength": 20,
+                    "minLength": 1
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_request.UpdateSchedulerRequest": {
+            "type": "object",
+            "properties": {
+                "batch_size": {
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 1
+                },
+                "concurrency": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1
+                },
+                "interval_in_seconds": {
+                    "type": "integer",
+                    "maximum": 86400,
+                    "minimum": 1
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_response.BatchMessageResult": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_response.BatchMessagesResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/go-template-microservice_internal_resources_response.BatchMessagesResult"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_response.BatchMessagesResult": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-template-microservice_internal_resources_response.BatchMessageResult"
+                    }
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_response.CursorPagination": {
+            "type": "object",
+            "properties": {
+                "has_more": {
+                    "type": "boolean"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_response.DispatchSummary": {
+            "type": "object",
+            "properties": {
+                "batch_size": {
+                    "type": "integer"
+                },
+                "expired": {
+                    "description": "Expired messages passed their expiry before they were sent and were not sent at all",
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "retried": {
+                    "type": "integer"
+                },
+                "sent": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "description": "Skipped messages were claimed but returned to the queue, e.g. after a 429 or on shutdown",
+                    "type": "integer"
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_response.DispatchSummaryResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/go-template-microservice_internal_resources_response.DispatchSummary"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_response.MessageResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/go-template-microservice_internal_models.Message"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_response.MessagesPaginationResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-template-microservice_internal_models.Message"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/go-template-microservice_internal_resources_response.CursorPagination"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_response.SchedulerSettings": {
+            "type": "object",
+            "properties": {
+                "batch_size": {
+                    "type": "integer"
+                },
+                "concurrency": {
+                    "type": "integer"
+                },
+                "interval_in_seconds": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_response.SchedulerStatus": {
+            "type": "object",
+            "properties": {
+                "circuit_breaker": {
+                    "description": "CircuitBreaker is the state of the webhook circuit breaker, empty when none is configured",
+                    "type": "string"
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "last_tick": {
+                    "$ref": "#/definitions/go-template-microservice_internal_resources_response.SchedulerTickStats"
+                },
+                "last_tick_at": {
+                    "type": "string"
+                },
+                "last_tick_duration_ms": {
+                    "type": "integer"
+                },
+                "leader": {
+                    "type": "string"
+                },
+                "next_tick_at": {
+                    "type": "string"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "sending_window_open": {
+                    "description": "SendingWindowOpen is false during quiet hours, when only exempt messages are sent",
+                    "type": "boolean"
+                },
+                "settings": {
+                    "$ref": "#/definitions/go-template-microservice_internal_resources_response.SchedulerSettings"
+                },
+                "since_start": {
+                    "$ref": "#/definitions/go-template-microservice_internal_resources_response.SchedulerTickStats"
+                },
+                "started_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_response.SchedulerStatusResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/go-template-microservice_internal_resources_response.SchedulerStatus"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_response.SchedulerTickStats": {
+            "type": "object",
+            "properties": {
+                "expired": {
+                    "description": "Expired messages passed their expiry before they were sent and were not sent at all",
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "retried": {
+                    "type": "integer"
+                },
+                "sent": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "description": "Skipped messages were claimed but returned to the queue, e.g. after a 429 or on shutdown",
+                    "type": "integer"
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_response.SentMessageResponse": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "external_message_id": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "integer"
+                },
+                "sent_at": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "go-template-microservice_internal_resources_response.SentMessagesResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-template-microservice_internal_resources_response.SentMessageResponse"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-template-microservice_pkg_utils.ErrorFields": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
                 }
             }
         },
@@ -190,6 +1132,26 @@ const docTemplate = `{
                     "type": "integer"
                 }
             }
+        },
+        "go-template-microservice_pkg_utils.HTTPValidationErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/go-template-microservice_pkg_utils.ErrorSchema"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-template-microservice_pkg_utils.ErrorFields"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
@@ -15,6 +15,7 @@ require (
 	github.com/swaggo/swag v1.16.6
 	github.com/valyala/fasthttp v1.51.0
 	go.uber.org/mock v0.6.0
+	golang.org/x/time v0.12.0
 )
 
 require (
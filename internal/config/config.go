@@ -10,12 +10,13 @@ type Config struct {
 }
 
 type ServerConfig struct {
-	AppVersion   string      `split_words:"true"`
-	HttpPort     string      `required:"true" split_words:"true" default:"8080"`
-	Environment  Environment `required:"true" split_words:"true" default:"local"`
-	LogLevel     string      `split_words:"true" default:"INFO"`
-	ReadTimeout  int         `split_words:"true" default:"5"`
-	WriteTimeout int         `split_words:"true" default:"10"`
+	AppVersion      string      `split_words:"true"`
+	HttpPort        string      `required:"true" split_words:"true" default:"8080"`
+	Environment     Environment `required:"true" split_words:"true" default:"local"`
+	LogLevel        string      `split_words:"true" default:"INFO"`
+	ReadTimeout     int         `split_words:"true" default:"5"`
+	WriteTimeout    int         `split_words:"true" default:"10"`
+	ShutdownTimeout int         `split_words:"true" default:"30"`
 }
 
 type HttpClientConfig struct {
@@ -24,13 +25,57 @@ type HttpClientConfig struct {
 }
 
 type WebhookConfig struct {
-	Url     string `split_words:"true" default:"http://localhost:9000/webhook"`
-	AuthKey string `split_words:"true"`
+	Url                string  `split_words:"true" default:"http://localhost:9000/webhook"`
+	AuthKey            string  `split_words:"true"`
+	RateLimitPerSecond float64 `split_words:"true" default:"0"`
+	RateLimitBurst     int     `split_words:"true" default:"1"`
+	// AuthType selects how requests are authenticated: header (AuthKey in AuthHeader), basic or oauth2
+	AuthType                    string   `split_words:"true" default:"header"`
+	AuthHeader                  string   `split_words:"true" default:"x-ins-auth-key"`
+	BasicUsername               string   `split_words:"true"`
+	BasicPassword               string   `split_words:"true"`
+	TokenUrl                    string   `split_words:"true"`
+	ClientId                    string   `split_words:"true"`
+	ClientSecret                string   `split_words:"true"`
+	TokenScopes                 []string `split_words:"true"`
+	TokenRefreshBeforeInSeconds int      `split_words:"true" default:"60"`
+	// Endpoints replace Url with weighted "<url>|<weight>" entries the json provider fails over between
+	Endpoints                      []string `split_words:"true"`
+	EndpointFailureThreshold       int      `split_words:"true" default:"3"`
+	EndpointProbeIntervalInSeconds int      `split_words:"true" default:"30"`
+	// SigningSecrets enable HMAC signing of the outbound body, every secret signs during a rotation
+	SigningSecrets  []string `split_words:"true"`
+	SignatureHeader string   `split_words:"true" default:"X-Webhook-Signature"`
+	// CircuitBreakerThreshold is the number of consecutive failures that open the circuit, 0 disables it
+	CircuitBreakerThreshold         int `split_words:"true" default:"0"`
+	CircuitBreakerCoolDownInSeconds int `split_words:"true" default:"30"`
+	// Provider is the delivery provider used when no routing rule matches a message
+	Provider     string   `split_words:"true" default:"json"`
+	Routes       []string `split_words:"true"`
+	FormUrl      string   `split_words:"true"`
+	SmtpAddr     string   `split_words:"true"`
+	SmtpUsername string   `split_words:"true"`
+	SmtpPassword string   `split_words:"true"`
+	SmtpFrom     string   `split_words:"true"`
+	SmtpDomain   string   `split_words:"true"`
 }
 
 type SchedulerConfig struct {
-	IntervalInSeconds int `split_words:"true" default:"120"`
-	BatchSize         int `split_words:"true" default:"2"`
+	IntervalInSeconds       int  `split_words:"true" default:"120"`
+	BatchSize               int  `split_words:"true" default:"2"`
+	MaxAttempts             int  `split_words:"true" default:"5"`
+	BackoffBaseInSeconds    int  `split_words:"true" default:"30"`
+	BackoffMaxInSeconds     int  `split_words:"true" default:"3600"`
+	LeaseInSeconds          int  `split_words:"true" default:"60"`
+	Concurrency             int  `split_words:"true" default:"1"`
+	LowPrioritySharePercent int  `split_words:"true" default:"10"`
+	AutoStart               bool `split_words:"true" default:"false"`
+	LeaderElection          bool `split_words:"true" default:"false"`
+	LeaderLeaseInSeconds    int  `split_words:"true" default:"15"`
+	// SendingWindows are separated by semicolons since cron expressions contain commas
+	SendingWindows         string   `split_words:"true"`
+	SendingTimezone        string   `split_words:"true" default:"UTC"`
+	WindowExemptCategories []string `split_words:"true"`
 }
 
 type DatabaseConfig struct {
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
+	"go-template-microservice/internal/repository"
 	"go-template-microservice/internal/resources/request"
+	"go-template-microservice/internal/resources/response"
 	"go-template-microservice/internal/services"
 	"go-template-microservice/pkg/utils"
 	"net/http"
@@ -13,7 +16,16 @@ import (
 type MessageHandler interface {
 	StartScheduler(c *fiber.Ctx) error
 	StopScheduler(c *fiber.Ctx) error
+	GetSchedulerStatus(c *fiber.Ctx) error
+	DispatchMessages(c *fiber.Ctx) error
+	UpdateScheduler(c *fiber.Ctx) error
 	ListSentMessages(c *fiber.Ctx) error
+	CreateMessage(c *fiber.Ctx) error
+	CreateMessagesBatch(c *fiber.Ctx) error
+	ListMessages(c *fiber.Ctx) error
+	GetMessage(c *fiber.Ctx) error
+	CancelMessage(c *fiber.Ctx) error
+	UpdateMessage(c *fiber.Ctx) error
 }
 
 type messageHandler struct {
@@ -24,19 +36,56 @@ type messageHandler struct {
 func NewMessageHandler(mmessageService services.MessageService, logger *logrus.Logger) MessageHandler {
 	return &messageHandler{
 		messageService: mmessageService,
+		logger:         logger,
 	}
 }
 
 func (h *messageHandler) StartScheduler(c *fiber.Ctx) error {
-	h.messageService.StartScheduler(c)
+	h.messageService.StartScheduler(c.UserContext())
 	return c.Status(http.StatusOK).JSON(utils.NewSuccessResponse(fiber.Map{"state": "started"}))
 }
 
 func (h *messageHandler) StopScheduler(c *fiber.Ctx) error {
-	h.messageService.StopScheduler(c)
+	h.messageService.StopScheduler(c.UserContext())
 	return c.Status(http.StatusOK).JSON(utils.NewSuccessResponse(fiber.Map{"state": "stopped"}))
 }
 
+func (h *messageHandler) GetSchedulerStatus(c *fiber.Ctx) error {
+	return c.Status(http.StatusOK).JSON(utils.NewSuccessResponse(h.messageService.SchedulerStatus()))
+}
+
+func (h *messageHandler) UpdateScheduler(c *fiber.Ctx) error {
+	var req request.UpdateSchedulerRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to parse UpdateSchedulerRequest")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(utils.NewBodyParserErrorResponse())
+	}
+
+	if err := utils.Validator(c.Context(), &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.NewValidationErrorResponse(err))
+	}
+
+	return c.Status(http.StatusOK).JSON(utils.NewSuccessResponse(h.messageService.UpdateScheduler(c.UserContext(), req)))
+}
+
+// DispatchMessages runs a single scheduler tick synchronously, the request body is optional
+func (h *messageHandler) DispatchMessages(c *fiber.Ctx) error {
+	var req request.DispatchMessagesRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			h.logger.WithError(err).Error("Failed to parse DispatchMessagesRequest")
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(utils.NewBodyParserErrorResponse())
+		}
+	}
+
+	if err := utils.Validator(c.Context(), &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.NewValidationErrorResponse(err))
+	}
+
+	summary := h.messageService.DispatchMessages(c.UserContext(), req.BatchSize)
+	return c.Status(http.StatusOK).JSON(utils.NewSuccessResponse(summary))
+}
+
 func (h *messageHandler) ListSentMessages(c *fiber.Ctx) error {
 	var req request.ListSentMessagesRequest
 	if err := c.QueryParser(&req); err != nil {
@@ -60,3 +109,169 @@ func (h *messageHandler) ListSentMessages(c *fiber.Ctx) error {
 	}
 	return c.Status(http.StatusOK).JSON(utils.NewSuccessResponse(messages))
 }
+
+func (h *messageHandler) ListMessages(c *fiber.Ctx) error {
+	var req request.ListMessagesRequest
+	if err := c.QueryParser(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to parse ListMessagesRequest")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(utils.NewBodyParserErrorResponse())
+	}
+
+	if err := utils.Validator(c.Context(), &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.NewValidationErrorResponse(err))
+	}
+
+	messages, pagination, err := h.messageService.ListMessages(req)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.NewValidationErrorResponse(map[string]string{"Cursor": err.Error()}))
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.NewErrorResponse(c.Context(), err))
+	}
+	return c.Status(http.StatusOK).JSON(utils.NewSuccessPaginationResponse(messages, pagination))
+}
+
+func (h *messageHandler) CreateMessage(c *fiber.Ctx) error {
+	var req request.CreateMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to parse CreateMessageRequest")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(utils.NewBodyParserErrorResponse())
+	}
+
+	if err := utils.Validator(c.Context(), &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.NewValidationErrorResponse(err))
+	}
+
+	message, err := h.messageService.CreateMessage(req)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.NewErrorResponse(c.Context(), err))
+	}
+	return c.Status(http.StatusCreated).JSON(utils.NewSuccessResponse(message))
+}
+
+// CreateMessagesBatch validates every item on its own so that a single invalid item
+// is reported back instead of rejecting the whole batch
+func (h *messageHandler) CreateMessagesBatch(c *fiber.Ctx) error {
+	var req request.CreateMessagesBatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to parse CreateMessagesBatchRequest")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(utils.NewBodyParserErrorResponse())
+	}
+
+	if err := utils.Validator(c.Context(), &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.NewValidationErrorResponse(err))
+	}
+
+	results := make([]response.BatchMessageResult, len(req.Messages))
+	valid := make([]request.CreateMessageRequest, 0, len(req.Messages))
+	validIndexes := make([]int, 0, len(req.Messages))
+	for i := range req.Messages {
+		if errs := utils.Validator(c.Context(), &req.Messages[i]); errs != nil {
+			results[i] = response.BatchMessageResult{Index: i, Status: response.BatchItemRejected, Errors: errs}
+			continue
+		}
+		valid = append(valid, req.Messages[i])
+		validIndexes = append(validIndexes, i)
+	}
+
+	created, err := h.messageService.CreateMessages(valid)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.NewErrorResponse(c.Context(), err))
+	}
+
+	for i, msg := range created {
+		idx := validIndexes[i]
+		results[idx] = response.BatchMessageResult{Index: idx, ID: msg.ID, Status: response.BatchItemCreated}
+	}
+
+	return c.Status(http.StatusOK).JSON(utils.NewSuccessResponse(response.BatchMessagesResult{
+		Created:  len(created),
+		Rejected: len(req.Messages) - len(created),
+		Results:  results,
+	}))
+}
+
+func (h *messageHandler) GetMessage(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.NewValidationErrorResponse(map[string]string{"id": "must be a message ID"}))
+	}
+
+	message, err := h.messageService.GetMessage(int64(id))
+	if err != nil {
+		return h.messageError(c, err)
+	}
+	return c.Status(http.StatusOK).JSON(utils.NewSuccessResponse(message))
+}
+
+func (h *messageHandler) CancelMessage(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.NewValidationErrorResponse(map[string]string{"id": "must be a message ID"}))
+	}
+
+	var req request.CancelMessageRequest
+	if err := c.QueryParser(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to parse CancelMessageRequest")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(utils.NewBodyParserErrorResponse())
+	}
+
+	if err := utils.Validator(c.Context(), &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.NewValidationErrorResponse(err))
+	}
+
+	message, err := h.messageService.CancelMessage(int64(id), req)
+	if err != nil {
+		return h.messageError(c, err)
+	}
+	return c.Status(http.StatusOK).JSON(utils.NewSuccessResponse(message))
+}
+
+func (h *messageHandler) UpdateMessage(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.NewValidationErrorResponse(map[string]string{"id": "must be a message ID"}))
+	}
+
+	var req request.UpdateMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to parse UpdateMessageRequest")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(utils.NewBodyParserErrorResponse())
+	}
+
+	if err := utils.Validator(c.Context(), &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.NewValidationErrorResponse(err))
+	}
+
+	message, err := h.messageService.UpdateMessage(int64(id), req)
+	if err != nil {
+		return h.messageError(c, err)
+	}
+	return c.Status(http.StatusOK).JSON(utils.NewSuccessResponse(message))
+}
+
+// messageError maps the errors of single message operations to their HTTP status
+func (h *messageHandler) messageError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrMessageNotFound):
+		return c.Status(http.StatusNotFound).JSON(utils.NewErrorResponse(c.Context(), utils.Error{
+			Code:    utils.NotFoundErrCode,
+			Reason:  err,
+			Message: utils.NotFoundMsg,
+		}))
+	case errors.Is(err, repository.ErrMessageNotPending):
+		return c.Status(http.StatusConflict).JSON(utils.NewErrorResponse(c.Context(), utils.Error{
+			Code:    utils.ConflictErrCode,
+			Reason:  err,
+			Message: "The message is no longer pending.",
+		}))
+	case errors.Is(err, repository.ErrMessageModified):
+		return c.Status(http.StatusConflict).JSON(utils.NewErrorResponse(c.Context(), utils.Error{
+			Code:    utils.ConflictErrCode,
+			Reason:  err,
+			Message: "The message was modified in the meantime.",
+		}))
+	default:
+		return c.Status(http.StatusInternalServerError).JSON(utils.NewErrorResponse(c.Context(), err))
+	}
+}
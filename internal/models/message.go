@@ -1,40 +1,126 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"go-template-microservice/pkg/sqlite"
+)
 
 type Status string
 
 const (
 	StatusPending Status = "PENDING"
+	StatusSending Status = "SENDING"
 	StatusSent    Status = "SENT"
 	StatusFailed  Status = "FAILED"
+	// StatusExpired marks messages whose expiry passed before they could be sent
+	StatusExpired Status = "EXPIRED"
+	// StatusCancelled marks messages withdrawn before they were sent
+	StatusCancelled Status = "CANCELLED"
 )
 
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
+)
+
+// Priorities lists the priority lanes from the most to the least urgent
+var Priorities = []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+// Rank orders priorities, lower ranks are sent first
+func (p Priority) Rank() int {
+	switch p {
+	case PriorityHigh:
+		return 0
+	case PriorityLow:
+		return 2
+	default:
+		return 1
+	}
+}
+
 type Message struct {
-	ID                int64     `json:"id"`
-	To                string    `json:"to"`
-	Content           string    `json:"content"`
-	Status            Status    `json:"status"`
-	ExternalMessageID string    `json:"external_message_id"`
-	SentAt            time.Time `json:"sent_at"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	ID                int64      `json:"id"`
+	To                string     `json:"to"`
+	Content           string     `json:"content"`
+	Status            Status     `json:"status"`
+	Priority          Priority   `json:"priority"`
+	Category          string     `json:"category"`
+	ExternalMessageID string     `json:"external_message_id"`
+	Endpoint          string     `json:"endpoint,omitempty"`
+	ScheduledAt       *time.Time `json:"scheduled_at,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	SentAt            time.Time  `json:"sent_at"`
+	Attempts          int        `json:"attempts"`
+	LastError         string     `json:"last_error,omitempty"`
+	NextAttemptAt     *time.Time `json:"next_attempt_at,omitempty"`
+	LeaseOwner        string     `json:"lease_owner,omitempty"`
+	LeaseExpiresAt    *time.Time `json:"lease_expires_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// DueAt returns the time the message becomes eligible for delivery
+func (m Message) DueAt() time.Time {
+	if m.ScheduledAt != nil {
+		return *m.ScheduledAt
+	}
+	return m.CreatedAt
 }
 
-// GetMessageSchema returns the SQL schema for creating the message table
-func GetMessageSchema() string {
-	return `
+// Expired reports whether the message may no longer be sent at the given time
+func (m Message) Expired(now time.Time) bool {
+	return m.ExpiresAt != nil && !now.Before(*m.ExpiresAt)
+}
+
+// GetMessageSchema returns the SQL schema for creating the message table and upgrading
+// tables created by earlier releases
+func GetMessageSchema() sqlite.Schema {
+	return sqlite.Schema{
+		Table: `
     CREATE TABLE IF NOT EXISTS messages (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     "to" VARCHAR(20) NOT NULL,
     content VARCHAR(160) NOT NULL,
     status VARCHAR(16) NOT NULL DEFAULT 'PENDING',
+    priority VARCHAR(8) NOT NULL DEFAULT 'normal',
+    category VARCHAR(32) NOT NULL DEFAULT '',
     external_message_id VARCHAR(64) NOT NULL,
+    endpoint VARCHAR(255) NOT NULL DEFAULT '',
+    scheduled_at DATETIME,
+    expires_at DATETIME,
     sent_at DATETIME,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at DATETIME,
+    lease_owner VARCHAR(128) NOT NULL DEFAULT '',
+    lease_expires_at DATETIME,
     created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
     );
-
+    `,
+		// every column added after the first release, with the same definition as in the table above
+		Columns: []sqlite.Column{
+			{Table: "messages", Name: "priority", Definition: "VARCHAR(8) NOT NULL DEFAULT 'normal'"},
+			{Table: "messages", Name: "category", Definition: "VARCHAR(32) NOT NULL DEFAULT ''"},
+			{Table: "messages", Name: "endpoint", Definition: "VARCHAR(255) NOT NULL DEFAULT ''"},
+			{Table: "messages", Name: "scheduled_at", Definition: "DATETIME"},
+			{Table: "messages", Name: "expires_at", Definition: "DATETIME"},
+			{Table: "messages", Name: "attempts", Definition: "INTEGER NOT NULL DEFAULT 0"},
+			{Table: "messages", Name: "last_error", Definition: "TEXT NOT NULL DEFAULT ''"},
+			{Table: "messages", Name: "next_attempt_at", Definition: "DATETIME"},
+			{Table: "messages", Name: "lease_owner", Definition: "VARCHAR(128) NOT NULL DEFAULT ''"},
+			{Table: "messages", Name: "lease_expires_at", Definition: "DATETIME"},
+		},
+		Indexes: `
     CREATE INDEX IF NOT EXISTS idx_messages_status_created_at ON messages(status, created_at);
-    `
+    CREATE INDEX IF NOT EXISTS idx_messages_status_due_at ON messages(status, COALESCE(scheduled_at, created_at));
+    CREATE INDEX IF NOT EXISTS idx_messages_status_priority_due_at ON messages(status, priority, COALESCE(scheduled_at, created_at));
+    CREATE INDEX IF NOT EXISTS idx_messages_status_expires_at ON messages(status, expires_at);
+    CREATE INDEX IF NOT EXISTS idx_messages_status_lease_expires_at ON messages(status, lease_expires_at);
+    `,
+	}
 }
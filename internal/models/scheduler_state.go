@@ -0,0 +1,17 @@
+package models
+
+// SchedulerState is the running state operators last requested for the scheduler
+type SchedulerState string
+
+const (
+	SchedulerStateRunning SchedulerState = "running"
+	SchedulerStateStopped SchedulerState = "stopped"
+)
+
+// SchedulerSettings are the scheduler tunables operators last changed at runtime, nil fields were
+// never changed and keep their configured value
+type SchedulerSettings struct {
+	IntervalInSeconds *int
+	BatchSize         *int
+	Concurrency       *int
+}
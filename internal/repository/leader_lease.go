@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-template-microservice/pkg/redis"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+type LeaderLeaseRepository interface {
+	// Acquire takes the lease for owner when nobody holds it and reports whether it succeeded
+	Acquire(ctx context.Context, owner string, ttl time.Duration) (bool, error)
+	// Renew extends the lease and reports false when owner no longer holds it
+	Renew(ctx context.Context, owner string, ttl time.Duration) (bool, error)
+	// Release gives the lease up, it is a no-op when owner doesn't hold it
+	Release(ctx context.Context, owner string) error
+	// GetLeader returns the current lease holder, empty when the lease is free
+	GetLeader(ctx context.Context) (string, error)
+}
+
+type leaderLeaseRepository struct {
+	redis  redis.IRedisInstance
+	logger *logrus.Logger
+}
+
+const (
+	// schedulerLeaderKey holds the identity of the replica running the scheduler
+	schedulerLeaderKey = "scheduler:leader"
+)
+
+var (
+	// renewLeaseScript extends the lease only if it is still held by the caller
+	renewLeaseScript = goredis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+		end
+		return 0
+	`)
+	// releaseLeaseScript deletes the lease only if it is still held by the caller
+	releaseLeaseScript = goredis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("DEL", KEYS[1])
+		end
+		return 0
+	`)
+)
+
+func NewLeaderLeaseRepository(redis redis.IRedisInstance, logger *logrus.Logger) LeaderLeaseRepository {
+	return &leaderLeaseRepository{
+		redis:  redis,
+		logger: logger,
+	}
+}
+
+func (r *leaderLeaseRepository) Acquire(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	acquired, err := r.redis.Client().SetNX(ctx, schedulerLeaderKey, owner, ttl).Result()
+	if err != nil {
+		r.logger.WithError(err).WithField("owner", owner).Error("Failed to acquire leader lease")
+		return false, fmt.Errorf("failed to acquire leader lease: %w", err)
+	}
+
+	return acquired, nil
+}
+
+func (r *leaderLeaseRepository) Renew(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	renewed, err := renewLeaseScript.Run(ctx, r.redis.Client(), []string{schedulerLeaderKey}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		r.logger.WithError(err).WithField("owner", owner).Error("Failed to renew leader lease")
+		return false, fmt.Errorf("failed to renew leader lease: %w", err)
+	}
+
+	return renewed == 1, nil
+}
+
+func (r *leaderLeaseRepository) Release(ctx context.Context, owner string) error {
+	if err := releaseLeaseScript.Run(ctx, r.redis.Client(), []string{schedulerLeaderKey}, owner).Err(); err != nil {
+		r.logger.WithError(err).WithField("owner", owner).Error("Failed to release leader lease")
+		return fmt.Errorf("failed to release leader lease: %w", err)
+	}
+
+	return nil
+}
+
+func (r *leaderLeaseRepository) GetLeader(ctx context.Context) (string, error) {
+	leader, err := r.redis.Client().Get(ctx, schedulerLeaderKey).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get leader")
+		return "", fmt.Errorf("failed to get leader: %w", err)
+	}
+
+	return leader, nil
+}
@@ -0,0 +1,83 @@
+package repository_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LeaderLeaseRepository", func() {
+	BeforeEach(func() {
+		err := mockRedis.Client().Del(ctx, "scheduler:leader").Err()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("Acquire", func() {
+		It("should grant the lease to a single owner", func() {
+			acquired, err := leaderLeaseRepo.Acquire(ctx, "replica-a", time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+
+			acquired, err = leaderLeaseRepo.Acquire(ctx, "replica-b", time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeFalse())
+
+			leader, err := leaderLeaseRepo.GetLeader(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(leader).To(Equal("replica-a"))
+
+			ttl, err := mockRedis.Client().PTTL(ctx, "scheduler:leader").Result()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ttl).To(BeNumerically(">", 0))
+			Expect(ttl).To(BeNumerically("<=", time.Minute))
+		})
+	})
+
+	Describe("Renew", func() {
+		It("should only extend the lease for its holder", func() {
+			_, err := leaderLeaseRepo.Acquire(ctx, "replica-a", time.Second)
+			Expect(err).NotTo(HaveOccurred())
+
+			renewed, err := leaderLeaseRepo.Renew(ctx, "replica-b", time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(renewed).To(BeFalse())
+
+			renewed, err = leaderLeaseRepo.Renew(ctx, "replica-a", time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(renewed).To(BeTrue())
+
+			ttl, err := mockRedis.Client().PTTL(ctx, "scheduler:leader").Result()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ttl).To(BeNumerically(">", time.Second))
+		})
+
+		It("should report a lease that is no longer held", func() {
+			renewed, err := leaderLeaseRepo.Renew(ctx, "replica-a", time.Minute)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(renewed).To(BeFalse())
+		})
+	})
+
+	Describe("Release", func() {
+		It("should only free the lease for its holder", func() {
+			_, err := leaderLeaseRepo.Acquire(ctx, "replica-a", time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(leaderLeaseRepo.Release(ctx, "replica-b")).To(Succeed())
+			leader, err := leaderLeaseRepo.GetLeader(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(leader).To(Equal("replica-a"))
+
+			Expect(leaderLeaseRepo.Release(ctx, "replica-a")).To(Succeed())
+			leader, err = leaderLeaseRepo.GetLeader(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(leader).To(BeEmpty())
+
+			acquired, err := leaderLeaseRepo.Acquire(ctx, "replica-b", time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+		})
+	})
+})
@@ -2,8 +2,12 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"go-template-microservice/internal/models"
 	"go-template-microservice/pkg/sqlite"
@@ -12,14 +16,98 @@ import (
 )
 
 type MessageRepository interface {
-	// GetUnsentMessages retrieves messages with PENDING status, limited by the given count
+	// GetUnsentMessages retrieves due messages with PENDING status ordered by due time, limited by the given count
 	GetUnsentMessages(limit int) ([]models.Message, error)
-	// UpdateMessageStatus updates the status of a message and optionally sets external message ID and sent time
-	UpdateMessageStatus(messageID int64, status models.Status, externalMessageID *string, sentAt *time.Time) error
+	// UpdateMessageStatus updates the status of a message leased by owner and optionally sets external message ID
+	// and sent time, ErrLeaseLost is returned when owner no longer holds the lease
+	UpdateMessageStatus(messageID int64, owner string, status models.Status, externalMessageID *string, sentAt *time.Time) error
+	// MarkMessageSent moves a message leased by owner to SENT and records the external message ID and the
+	// endpoint it was sent to, ErrLeaseLost is returned when owner no longer holds the lease
+	MarkMessageSent(messageID int64, owner, externalMessageID, endpoint string, sentAt time.Time) error
 	// CreateMessage creates a new message record in the database
-	CreateMessage(to, content string) (*models.Message, error)
+	CreateMessage(message models.Message) (*models.Message, error)
+	// CreateMessages creates the given messages with PENDING status in a single transaction
+	CreateMessages(messages []models.Message) ([]models.Message, error)
 	// GetSentMessages retrieves messages with SENT status, limited by the given count and ordered by sent_at descending
 	GetSentMessages(limit int) ([]models.Message, error)
+	// RecordSendFailure increments the attempt counter of a message leased by owner and sets the status, last
+	// error and next attempt time, ErrLeaseLost is returned when owner no longer holds the lease
+	RecordSendFailure(messageID int64, owner string, status models.Status, lastError string, nextAttemptAt *time.Time) error
+	// ClaimMessages atomically moves due PENDING messages to SENDING under a lease owned by the caller
+	ClaimMessages(opts ClaimOptions) ([]models.Message, error)
+	// ReleaseClaims returns messages still leased by the given owner back to PENDING
+	ReleaseClaims(owner string, messageIDs []int64) error
+	// ReleaseExpiredLeases returns SENDING messages whose lease has expired back to PENDING
+	ReleaseExpiredLeases() (int64, error)
+	// ExpireMessages moves PENDING messages whose expiry has passed to EXPIRED and returns their count
+	ExpireMessages() (int64, error)
+	// GetMessage retrieves a single message, ErrMessageNotFound is returned when it doesn't exist
+	GetMessage(messageID int64) (*models.Message, error)
+	// CancelMessage moves a PENDING message to CANCELLED if it wasn't modified since updatedAt
+	CancelMessage(messageID int64, updatedAt time.Time) (*models.Message, error)
+	// UpdatePendingMessage applies the changes to a PENDING message if it wasn't modified since updatedAt
+	UpdatePendingMessage(messageID int64, updatedAt time.Time, changes MessageChanges) (*models.Message, error)
+	// ListMessages retrieves messages matching the filter, newest first by the filter's sort column
+	ListMessages(filter MessageFilter) ([]models.Message, error)
+}
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	// ErrMessageNotPending is returned when a message can no longer be changed because it is
+	// being sent or has reached a final status
+	ErrMessageNotPending = errors.New("message is not pending")
+	// ErrMessageModified is returned when a message was modified after it was read
+	ErrMessageModified = errors.New("message was modified concurrently")
+	// ErrLeaseLost is returned when a worker completes a message whose lease it no longer holds,
+	// the lease expired and the message was released or claimed by another worker
+	ErrLeaseLost = errors.New("message lease lost")
+)
+
+// ClaimOptions describes which messages a worker claims and for how long it owns them
+type ClaimOptions struct {
+	Owner         string
+	Limit         int
+	LeaseDuration time.Duration
+	// Priority restricts the claim to a single priority lane, all lanes are claimed when empty
+	Priority models.Priority
+	// Categories restricts the claim to messages of the given categories, all are claimed when empty
+	Categories []string
+}
+
+// MessageChanges holds the editable fields of a PENDING message, nil fields are left unchanged
+type MessageChanges struct {
+	To      *string
+	Content *string
+}
+
+// MessageSortField is the column messages are listed and paginated by
+type MessageSortField string
+
+const (
+	SortByCreatedAt MessageSortField = "created_at"
+	SortBySentAt    MessageSortField = "sent_at"
+)
+
+// MessageFilter describes which messages ListMessages returns. Empty fields don't filter, time
+// ranges include their start and exclude their end.
+type MessageFilter struct {
+	Statuses    []models.Status
+	To          string
+	Content     string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	SentFrom    *time.Time
+	SentTo      *time.Time
+	SortBy      MessageSortField
+	// After continues the listing right after the given position of a previous page
+	After *MessageCursor
+	Limit int
+}
+
+// MessageCursor is a position in a listing, the value of the sort column and the ID of a message
+type MessageCursor struct {
+	Time time.Time
+	ID   int64
 }
 
 type messageRepository struct {
@@ -34,43 +122,71 @@ func NewMessageRepository(sqlite sqlite.ISqliteInstance, logger *logrus.Logger)
 	}
 }
 
-func (r *messageRepository) GetUnsentMessages(limit int) ([]models.Message, error) {
-	query := `
-		SELECT id, "to", content, status, external_message_id, sent_at, created_at, updated_at
-		FROM messages
-		WHERE status = ?
-		ORDER BY created_at ASC
-		LIMIT ?
-	`
+// messageColumns is the column list every message query selects, in the order scanMessage expects
+const messageColumns = `id, "to", content, status, priority, category, external_message_id, endpoint, scheduled_at, expires_at, sent_at, attempts, last_error, next_attempt_at, lease_owner, lease_expires_at, created_at, updated_at`
 
-	rows, err := r.db.Query(query, models.StatusPending, limit)
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMessage(row rowScanner) (models.Message, error) {
+	var msg models.Message
+	var scheduledAt, expiresAt, sentAt, nextAttemptAt, leaseExpiresAt sql.NullTime
+	err := row.Scan(
+		&msg.ID,
+		&msg.To,
+		&msg.Content,
+		&msg.Status,
+		&msg.Priority,
+		&msg.Category,
+		&msg.ExternalMessageID,
+		&msg.Endpoint,
+		&scheduledAt,
+		&expiresAt,
+		&sentAt,
+		&msg.Attempts,
+		&msg.LastError,
+		&nextAttemptAt,
+		&msg.LeaseOwner,
+		&leaseExpiresAt,
+		&msg.CreatedAt,
+		&msg.UpdatedAt,
+	)
 	if err != nil {
-		r.logger.WithError(err).Error("Failed to query unsent messages")
-		return nil, fmt.Errorf("failed to query unsent messages: %w", err)
+		return msg, err
+	}
+	if scheduledAt.Valid {
+		msg.ScheduledAt = &scheduledAt.Time
+	}
+	if expiresAt.Valid {
+		msg.ExpiresAt = &expiresAt.Time
+	}
+	if sentAt.Valid {
+		msg.SentAt = sentAt.Time
+	}
+	if nextAttemptAt.Valid {
+		msg.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if leaseExpiresAt.Valid {
+		msg.LeaseExpiresAt = &leaseExpiresAt.Time
+	}
+	return msg, nil
+}
+
+func (r *messageRepository) queryMessages(query string, args ...any) ([]models.Message, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
 	var messages []models.Message
 	for rows.Next() {
-		var msg models.Message
-		var sentAt sql.NullTime
-		err := rows.Scan(
-			&msg.ID,
-			&msg.To,
-			&msg.Content,
-			&msg.Status,
-			&msg.ExternalMessageID,
-			&sentAt,
-			&msg.CreatedAt,
-			&msg.UpdatedAt,
-		)
+		msg, err := scanMessage(rows)
 		if err != nil {
 			r.logger.WithError(err).Error("Failed to scan message row")
 			return nil, fmt.Errorf("failed to scan message row: %w", err)
 		}
-		if sentAt.Valid {
-			msg.SentAt = sentAt.Time
-		}
 		messages = append(messages, msg)
 	}
 
@@ -79,15 +195,44 @@ func (r *messageRepository) GetUnsentMessages(limit int) ([]models.Message, erro
 		return nil, fmt.Errorf("error iterating message rows: %w", err)
 	}
 
+	return messages, nil
+}
+
+// dueCondition matches PENDING messages that may be sent now: the scheduled time, if any, has passed
+// and no retry is pending. It expects the status followed by the current time twice as arguments.
+const dueCondition = `status = ?
+	AND (scheduled_at IS NULL OR scheduled_at <= ?)
+	AND (next_attempt_at IS NULL OR next_attempt_at <= ?)`
+
+// dueOrder sorts by the time a message became due, which is its scheduled time or its creation time
+const dueOrder = `COALESCE(scheduled_at, created_at) ASC, id ASC`
+
+// GetUnsentMessages returns due PENDING messages, the ones that became due first come first
+func (r *messageRepository) GetUnsentMessages(limit int) ([]models.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE ` + dueCondition + `
+		ORDER BY ` + dueOrder + `
+		LIMIT ?
+	`
+
+	now := time.Now()
+	messages, err := r.queryMessages(query, models.StatusPending, now, now, limit)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to query unsent messages")
+		return nil, fmt.Errorf("failed to query unsent messages: %w", err)
+	}
+
 	r.logger.WithField("count", len(messages)).Debug("Retrieved unsent messages")
 	return messages, nil
 }
 
-func (r *messageRepository) UpdateMessageStatus(messageID int64, status models.Status, externalMessageID *string, sentAt *time.Time) error {
+func (r *messageRepository) UpdateMessageStatus(messageID int64, owner string, status models.Status, externalMessageID *string, sentAt *time.Time) error {
 	query := `
 		UPDATE messages
-		SET status = ?, external_message_id = ?, sent_at = ?, updated_at = ?
-		WHERE id = ?
+		SET status = ?, external_message_id = ?, sent_at = ?, lease_owner = '', lease_expires_at = NULL, updated_at = ?
+		WHERE id = ? AND lease_owner = ? AND status = ?
 	`
 
 	now := time.Now()
@@ -95,12 +240,53 @@ func (r *messageRepository) UpdateMessageStatus(messageID int64, status models.S
 	if externalMessageID != nil {
 		extID = *externalMessageID
 	}
-	result, err := r.db.Exec(query, status, extID, sentAt, now, messageID)
+	result, err := r.db.Exec(query, status, extID, sentAt, now, messageID, owner, models.StatusSending)
 	if err != nil {
 		r.logger.WithError(err).WithField("messageID", messageID).Error("Failed to update message status")
 		return fmt.Errorf("failed to update message status: %w", err)
 	}
 
+	if err := r.checkLeaseHeld(result, messageID, owner); err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"messageID": messageID,
+		"status":    status,
+	}).Debug("Message status updated successfully")
+
+	return nil
+}
+
+func (r *messageRepository) MarkMessageSent(messageID int64, owner, externalMessageID, endpoint string, sentAt time.Time) error {
+	query := `
+		UPDATE messages
+		SET status = ?, external_message_id = ?, endpoint = ?, sent_at = ?, lease_owner = '', lease_expires_at = NULL, updated_at = ?
+		WHERE id = ? AND lease_owner = ? AND status = ?
+	`
+
+	result, err := r.db.Exec(query, models.StatusSent, externalMessageID, endpoint, sentAt.Local(), time.Now(), messageID, owner, models.StatusSending)
+	if err != nil {
+		r.logger.WithError(err).WithField("messageID", messageID).Error("Failed to mark message as sent")
+		return fmt.Errorf("failed to mark message as sent: %w", err)
+	}
+
+	if err := r.checkLeaseHeld(result, messageID, owner); err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"messageID": messageID,
+		"endpoint":  endpoint,
+	}).Debug("Message marked as sent")
+
+	return nil
+}
+
+// checkLeaseHeld turns an update of a leased message that matched no row into ErrLeaseLost. The
+// completion writes only match while owner still holds the lease, once it expired the message may
+// already be sent or failed by the worker that claimed it next.
+func (r *messageRepository) checkLeaseHeld(result sql.Result, messageID int64, owner string) error {
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to get rows affected")
@@ -108,31 +294,61 @@ func (r *messageRepository) UpdateMessageStatus(messageID int64, status models.S
 	}
 
 	if rowsAffected == 0 {
-		r.logger.WithField("messageID", messageID).Warn("No message found with given ID")
-		return fmt.Errorf("no message found with ID: %d", messageID)
+		r.logger.WithFields(logrus.Fields{
+			"messageID": messageID,
+			"owner":     owner,
+		}).Warn("Message is no longer leased by the owner")
+		return ErrLeaseLost
 	}
+	return nil
+}
 
-	r.logger.WithFields(logrus.Fields{
-		"messageID": messageID,
-		"status":    status,
-	}).Debug("Message status updated successfully")
+// insertMessageQuery is shared by the single and the batch insert, the values come from insertArgs
+const insertMessageQuery = `
+	INSERT INTO messages ("to", content, status, priority, category, external_message_id, scheduled_at, expires_at, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
 
-	return nil
+// newPendingMessage prepares a message for insertion. Client supplied timestamps are converted to
+// local time like every other timestamp we store, SQLite compares them as text so they must share
+// the same offset.
+func newPendingMessage(msg models.Message, now time.Time) models.Message {
+	pending := models.Message{
+		To:                msg.To,
+		Content:           msg.Content,
+		Status:            models.StatusPending,
+		Priority:          msg.Priority,
+		Category:          msg.Category,
+		ExternalMessageID: "",
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if pending.Priority == "" {
+		pending.Priority = models.PriorityNormal
+	}
+	if msg.ScheduledAt != nil {
+		scheduledAt := msg.ScheduledAt.Local()
+		pending.ScheduledAt = &scheduledAt
+	}
+	if msg.ExpiresAt != nil {
+		expiresAt := msg.ExpiresAt.Local()
+		pending.ExpiresAt = &expiresAt
+	}
+	return pending
+}
+
+func insertArgs(msg models.Message) []any {
+	return []any{msg.To, msg.Content, msg.Status, msg.Priority, msg.Category, msg.ExternalMessageID, msg.ScheduledAt, msg.ExpiresAt, msg.CreatedAt, msg.UpdatedAt}
 }
 
 // CreateMessage creates a new message with PENDING status
-func (r *messageRepository) CreateMessage(to, content string) (*models.Message, error) {
-	if len(content) > 160 {
+func (r *messageRepository) CreateMessage(message models.Message) (*models.Message, error) {
+	if utf8.RuneCountInString(message.Content) > 160 {
 		return nil, fmt.Errorf("content exceeds 160 character limit")
 	}
 
-	query := `
-		INSERT INTO messages ("to", content, status, external_message_id, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`
-
-	now := time.Now()
-	result, err := r.db.Exec(query, to, content, models.StatusPending, "", now, now)
+	pending := newPendingMessage(message, time.Now())
+	result, err := r.db.Exec(insertMessageQuery, insertArgs(pending)...)
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to create message")
 		return nil, fmt.Errorf("failed to create message: %w", err)
@@ -143,62 +359,391 @@ func (r *messageRepository) CreateMessage(to, content string) (*models.Message,
 		r.logger.WithError(err).Error("Failed to get last insert ID")
 		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
 	}
+	pending.ID = id
 
-	message := &models.Message{
-		ID:                id,
-		To:                to,
-		Content:           content,
-		Status:            models.StatusPending,
-		ExternalMessageID: "",
-		CreatedAt:         now,
-		UpdatedAt:         now,
+	r.logger.WithField("messageID", id).Debug("Message created successfully")
+	return &pending, nil
+}
+
+// CreateMessages inserts all messages in one transaction, either every message is created or none is
+func (r *messageRepository) CreateMessages(messages []models.Message) ([]models.Message, error) {
+	for i, msg := range messages {
+		if utf8.RuneCountInString(msg.Content) > 160 {
+			return nil, fmt.Errorf("content of message at index %d exceeds 160 character limit", i)
+		}
 	}
 
-	r.logger.WithField("messageID", id).Debug("Message created successfully")
-	return message, nil
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to begin transaction")
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(insertMessageQuery)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to prepare batch insert")
+		return nil, fmt.Errorf("failed to prepare batch insert: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	created := make([]models.Message, 0, len(messages))
+	for _, msg := range messages {
+		pending := newPendingMessage(msg, now)
+		result, err := stmt.Exec(insertArgs(pending)...)
+		if err != nil {
+			r.logger.WithError(err).Error("Failed to create message in batch")
+			return nil, fmt.Errorf("failed to create message: %w", err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			r.logger.WithError(err).Error("Failed to get last insert ID")
+			return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+		pending.ID = id
+
+		created = append(created, pending)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Failed to commit batch insert")
+		return nil, fmt.Errorf("failed to commit batch insert: %w", err)
+	}
+
+	r.logger.WithField("count", len(created)).Debug("Messages created successfully")
+	return created, nil
 }
 
 func (r *messageRepository) GetSentMessages(limit int) ([]models.Message, error) {
 	query := `
-		SELECT id, "to", content, status, external_message_id, sent_at, created_at, updated_at
+		SELECT ` + messageColumns + `
 		FROM messages
 		WHERE status = ?
 		ORDER BY sent_at DESC
 		LIMIT ?
 	`
 
-	rows, err := r.db.Query(query, models.StatusSent, limit)
+	messages, err := r.queryMessages(query, models.StatusSent, limit)
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to query sent messages")
 		return nil, fmt.Errorf("failed to query sent messages: %w", err)
 	}
-	defer rows.Close()
 
-	var messages []models.Message
-	for rows.Next() {
-		var msg models.Message
-		err := rows.Scan(
-			&msg.ID,
-			&msg.To,
-			&msg.Content,
-			&msg.Status,
-			&msg.ExternalMessageID,
-			&msg.SentAt,
-			&msg.CreatedAt,
-			&msg.UpdatedAt,
+	r.logger.WithField("count", len(messages)).Debug("Retrieved sent messages")
+	return messages, nil
+}
+
+// RecordSendFailure increments the attempt counter of a message and stores the last error.
+// The status is either kept PENDING with the given next attempt time, or set to FAILED when retries are exhausted
+func (r *messageRepository) RecordSendFailure(messageID int64, owner string, status models.Status, lastError string, nextAttemptAt *time.Time) error {
+	query := `
+		UPDATE messages
+		SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ?,
+			lease_owner = '', lease_expires_at = NULL, updated_at = ?
+		WHERE id = ? AND lease_owner = ? AND status = ?
+	`
+
+	result, err := r.db.Exec(query, status, lastError, nextAttemptAt, time.Now(), messageID, owner, models.StatusSending)
+	if err != nil {
+		r.logger.WithError(err).WithField("messageID", messageID).Error("Failed to record send failure")
+		return fmt.Errorf("failed to record send failure: %w", err)
+	}
+
+	if err := r.checkLeaseHeld(result, messageID, owner); err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"messageID": messageID,
+		"status":    status,
+	}).Debug("Message send failure recorded")
+
+	return nil
+}
+
+// ClaimMessages selects due PENDING messages and marks them SENDING in a single UPDATE ... RETURNING
+// statement, so two workers can never claim the same message. The lease lets another worker pick the
+// message up again if the owner dies before completing it.
+func (r *messageRepository) ClaimMessages(opts ClaimOptions) ([]models.Message, error) {
+	now := time.Now()
+	args := []any{models.StatusSending, opts.Owner, now.Add(opts.LeaseDuration), now, models.StatusPending, now, now}
+
+	filter := ""
+	if opts.Priority != "" {
+		filter += " AND priority = ?"
+		args = append(args, opts.Priority)
+	}
+	if len(opts.Categories) > 0 {
+		filter += " AND category IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(opts.Categories)), ", ") + ")"
+		for _, category := range opts.Categories {
+			args = append(args, category)
+		}
+	}
+	args = append(args, opts.Limit)
+
+	query := `
+		UPDATE messages
+		SET status = ?, lease_owner = ?, lease_expires_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id
+			FROM messages
+			WHERE ` + dueCondition + filter + `
+			ORDER BY ` + dueOrder + `
+			LIMIT ?
 		)
-		if err != nil {
-			r.logger.WithError(err).Error("Failed to scan message row")
-			return nil, fmt.Errorf("failed to scan message row: %w", err)
+		RETURNING ` + messageColumns
+
+	messages, err := r.queryMessages(query, args...)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to claim messages")
+		return nil, fmt.Errorf("failed to claim messages: %w", err)
+	}
+
+	// RETURNING does not guarantee any order
+	sort.SliceStable(messages, func(i, j int) bool {
+		if messages[i].DueAt().Equal(messages[j].DueAt()) {
+			return messages[i].ID < messages[j].ID
 		}
-		messages = append(messages, msg)
+		return messages[i].DueAt().Before(messages[j].DueAt())
+	})
+
+	r.logger.WithFields(logrus.Fields{
+		"owner":      opts.Owner,
+		"priority":   opts.Priority,
+		"categories": opts.Categories,
+		"count":      len(messages),
+	}).Debug("Claimed messages")
+	return messages, nil
+}
+
+func (r *messageRepository) ReleaseClaims(owner string, messageIDs []int64) error {
+	if len(messageIDs) == 0 {
+		return nil
 	}
 
-	if err := rows.Err(); err != nil {
-		r.logger.WithError(err).Error("Error iterating message rows")
-		return nil, fmt.Errorf("error iterating message rows: %w", err)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(messageIDs)), ", ")
+	query := `
+		UPDATE messages
+		SET status = ?, lease_owner = '', lease_expires_at = NULL, updated_at = ?
+		WHERE status = ? AND lease_owner = ? AND id IN (` + placeholders + `)
+	`
+
+	args := []any{models.StatusPending, time.Now(), models.StatusSending, owner}
+	for _, id := range messageIDs {
+		args = append(args, id)
 	}
 
-	r.logger.WithField("count", len(messages)).Debug("Retrieved sent messages")
+	if _, err := r.db.Exec(query, args...); err != nil {
+		r.logger.WithError(err).WithField("owner", owner).Error("Failed to release claimed messages")
+		return fmt.Errorf("failed to release claimed messages: %w", err)
+	}
+
+	return nil
+}
+
+func (r *messageRepository) ReleaseExpiredLeases() (int64, error) {
+	query := `
+		UPDATE messages
+		SET status = ?, lease_owner = '', lease_expires_at = NULL, updated_at = ?
+		WHERE status = ? AND lease_expires_at <= ?
+	`
+
+	now := time.Now()
+	result, err := r.db.Exec(query, models.StatusPending, now, models.StatusSending, now)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to release expired leases")
+		return 0, fmt.Errorf("failed to release expired leases: %w", err)
+	}
+
+	released, err := result.RowsAffected()
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get rows affected")
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if released > 0 {
+		r.logger.WithField("count", released).Warn("Released messages with expired leases")
+	}
+	return released, nil
+}
+
+func (r *messageRepository) ExpireMessages() (int64, error) {
+	query := `
+		UPDATE messages
+		SET status = ?, updated_at = ?
+		WHERE status = ? AND expires_at IS NOT NULL AND expires_at <= ?
+	`
+
+	now := time.Now()
+	result, err := r.db.Exec(query, models.StatusExpired, now, models.StatusPending, now)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to expire messages")
+		return 0, fmt.Errorf("failed to expire messages: %w", err)
+	}
+
+	expired, err := result.RowsAffected()
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get rows affected")
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if expired > 0 {
+		r.logger.WithField("count", expired).Info("Expired stale messages")
+	}
+	return expired, nil
+}
+
+func (r *messageRepository) GetMessage(messageID int64) (*models.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE id = ?
+	`
+
+	msg, err := scanMessage(r.db.QueryRow(query, messageID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("messageID", messageID).Error("Failed to get message")
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	return &msg, nil
+}
+
+func (r *messageRepository) CancelMessage(messageID int64, updatedAt time.Time) (*models.Message, error) {
+	query := `
+		UPDATE messages
+		SET status = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND updated_at = ?
+		RETURNING ` + messageColumns
+
+	msg, err := r.updatePending(query, messageID, models.StatusCancelled, time.Now(), messageID, models.StatusPending, updatedAt.Local())
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.WithField("messageID", messageID).Debug("Message cancelled")
+	return msg, nil
+}
+
+func (r *messageRepository) UpdatePendingMessage(messageID int64, updatedAt time.Time, changes MessageChanges) (*models.Message, error) {
+	if changes.Content != nil && utf8.RuneCountInString(*changes.Content) > 160 {
+		return nil, fmt.Errorf("content exceeds 160 character limit")
+	}
+
+	query := `
+		UPDATE messages
+		SET "to" = COALESCE(?, "to"), content = COALESCE(?, content), updated_at = ?
+		WHERE id = ? AND status = ? AND updated_at = ?
+		RETURNING ` + messageColumns
+
+	msg, err := r.updatePending(query, messageID, changes.To, changes.Content, time.Now(), messageID, models.StatusPending, updatedAt.Local())
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.WithField("messageID", messageID).Debug("Message updated")
+	return msg, nil
+}
+
+// updatePending runs an UPDATE ... RETURNING guarded by the PENDING status and the updated_at
+// the caller read. When no row matched it finds out why so the caller gets a meaningful error.
+func (r *messageRepository) updatePending(query string, messageID int64, args ...any) (*models.Message, error) {
+	msg, err := scanMessage(r.db.QueryRow(query, args...))
+	if err == nil {
+		return &msg, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		r.logger.WithError(err).WithField("messageID", messageID).Error("Failed to update pending message")
+		return nil, fmt.Errorf("failed to update pending message: %w", err)
+	}
+
+	current, err := r.GetMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status != models.StatusPending {
+		return nil, ErrMessageNotPending
+	}
+	return nil, ErrMessageModified
+}
+
+// likeEscaper escapes the LIKE wildcards of a user supplied substring, the query uses ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ListMessages pages through messages with keyset pagination: rows are ordered by the sort
+// column and the ID descending, and a page starts strictly after the cursor of the previous one
+// so inserts and updates in between neither skip nor repeat rows.
+func (r *messageRepository) ListMessages(filter MessageFilter) ([]models.Message, error) {
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = SortByCreatedAt
+	}
+
+	var (
+		conditions []string
+		args       []any
+	)
+	if len(filter.Statuses) > 0 {
+		conditions = append(conditions, "status IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(filter.Statuses)), ", ")+")")
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if filter.To != "" {
+		conditions = append(conditions, `"to" = ?`)
+		args = append(args, filter.To)
+	}
+	if filter.Content != "" {
+		conditions = append(conditions, `content LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+likeEscaper.Replace(filter.Content)+"%")
+	}
+	// SQLite compares the timestamps as text, they must share the offset of the stored ones
+	for _, bound := range []struct {
+		condition string
+		value     *time.Time
+	}{
+		{"created_at >= ?", filter.CreatedFrom},
+		{"created_at < ?", filter.CreatedTo},
+		{"sent_at >= ?", filter.SentFrom},
+		{"sent_at < ?", filter.SentTo},
+	} {
+		if bound.value != nil {
+			conditions = append(conditions, bound.condition)
+			args = append(args, bound.value.Local())
+		}
+	}
+	if sortBy == SortBySentAt {
+		conditions = append(conditions, "sent_at IS NOT NULL")
+	}
+	if filter.After != nil {
+		conditions = append(conditions, "("+string(sortBy)+" < ? OR ("+string(sortBy)+" = ? AND id < ?))")
+		after := filter.After.Time.Local()
+		args = append(args, after, after, filter.After.ID)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		` + where + `
+		ORDER BY ` + string(sortBy) + ` DESC, id DESC
+		LIMIT ?
+	`
+	args = append(args, filter.Limit)
+
+	messages, err := r.queryMessages(query, args...)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to list messages")
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	r.logger.WithField("count", len(messages)).Debug("Listed messages")
 	return messages, nil
 }
@@ -4,18 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"go-template-microservice/internal/models"
 	"go-template-microservice/pkg/redis"
 
+	goredis "github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 )
 
 type MessageCacheRepository interface {
-	// CacheSentMessage caches the sent message with full details
+	// CacheSentMessage caches the sent message with full details and adds it to the sent_at index
 	CacheSentMessage(ctx context.Context, message models.SentMessageCache) error
-	// GetAllSentMessages retrieves all cached sent messages with limit
+	// GetAllSentMessages retrieves up to limit cached sent messages, the most recently sent first
 	GetAllSentMessages(ctx context.Context, limit int) ([]models.SentMessageCache, error)
 }
 
@@ -28,6 +30,9 @@ type messageCacheRepository struct {
 const (
 	// sentMessageKeyPrefix is the prefix for sent message cache keys
 	sentMessageKeyPrefix = "sent_message:"
+	// sentMessageIndexKey is a sorted set of the cached message IDs scored by their sent_at in
+	// milliseconds, it orders the cache without reading every entry
+	sentMessageIndexKey = "sent_messages:index"
 )
 
 func NewMessageCacheRepository(redis redis.IRedisInstance, ttl time.Duration, logger *logrus.Logger) MessageCacheRepository {
@@ -38,8 +43,12 @@ func NewMessageCacheRepository(redis redis.IRedisInstance, ttl time.Duration, lo
 	}
 }
 
+func sentMessageKey(messageID string) string {
+	return sentMessageKeyPrefix + messageID
+}
+
 func (r *messageCacheRepository) CacheSentMessage(ctx context.Context, message models.SentMessageCache) error {
-	key := fmt.Sprintf("%s%d", sentMessageKeyPrefix, message.MessageID)
+	member := strconv.FormatInt(message.MessageID, 10)
 
 	jsonData, err := json.Marshal(message)
 	if err != nil {
@@ -47,7 +56,12 @@ func (r *messageCacheRepository) CacheSentMessage(ctx context.Context, message m
 		return fmt.Errorf("failed to marshal cache data: %w", err)
 	}
 
-	err = r.redis.Client().Set(ctx, key, jsonData, r.ttl).Err()
+	_, err = r.redis.Client().TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Set(ctx, sentMessageKey(member), jsonData, r.ttl)
+		pipe.ZAdd(ctx, sentMessageIndexKey, goredis.Z{Score: float64(message.SentAt.UnixMilli()), Member: member})
+		r.trimIndex(ctx, pipe)
+		return nil
+	})
 	if err != nil {
 		r.logger.WithError(err).WithField("messageID", message.MessageID).Error("Failed to cache sent message")
 		return fmt.Errorf("failed to cache sent message: %w", err)
@@ -62,42 +76,73 @@ func (r *messageCacheRepository) CacheSentMessage(ctx context.Context, message m
 	return nil
 }
 
-// GetAllSentMessages retrieves all cached sent messages using SCAN command
-// It returns up to 'limit' messages from the cache
+// trimIndex drops index members sent longer than the TTL ago, their entries have expired since
+// they are cached after being sent
+func (r *messageCacheRepository) trimIndex(ctx context.Context, cmd goredis.Cmdable) *goredis.IntCmd {
+	expiredBefore := time.Now().Add(-r.ttl).UnixMilli()
+	return cmd.ZRemRangeByScore(ctx, sentMessageIndexKey, "-inf", "("+strconv.FormatInt(expiredBefore, 10))
+}
+
+// GetAllSentMessages walks the index from the newest member down and loads the entries in
+// batches. Members whose entry expired before the index was trimmed are removed on the way.
 func (r *messageCacheRepository) GetAllSentMessages(ctx context.Context, limit int) ([]models.SentMessageCache, error) {
-	var messages []models.SentMessageCache
-	var cursor uint64
-	pattern := sentMessageKeyPrefix + "*"
+	if limit <= 0 {
+		return []models.SentMessageCache{}, nil
+	}
 
-	for {
-		keys, nextCursor, err := r.redis.Client().Scan(ctx, cursor, pattern, int64(limit)).Result()
+	if err := r.trimIndex(ctx, r.redis.Client()).Err(); err != nil {
+		r.logger.WithError(err).Warn("Failed to trim sent message index")
+	}
+
+	messages := make([]models.SentMessageCache, 0, limit)
+	var offset int64
+	for len(messages) < limit {
+		want := int64(limit - len(messages))
+		members, err := r.redis.Client().ZRevRange(ctx, sentMessageIndexKey, offset, offset+want-1).Result()
 		if err != nil {
-			r.logger.WithError(err).Error("Failed to scan cache keys")
-			return nil, fmt.Errorf("failed to scan cache keys: %w", err)
+			r.logger.WithError(err).Error("Failed to read sent message index")
+			return nil, fmt.Errorf("failed to read sent message index: %w", err)
+		}
+		if len(members) == 0 {
+			break
 		}
 
-		for _, key := range keys {
-			if len(messages) >= limit {
-				break
-			}
+		keys := make([]string, len(members))
+		for i, member := range members {
+			keys[i] = sentMessageKey(member)
+		}
+		values, err := r.redis.Client().MGet(ctx, keys...).Result()
+		if err != nil {
+			r.logger.WithError(err).Error("Failed to get cached messages")
+			return nil, fmt.Errorf("failed to get cached messages: %w", err)
+		}
 
-			result, err := r.redis.Client().Get(ctx, key).Result()
-			if err != nil {
-				r.logger.WithError(err).WithField("key", key).Warn("Failed to get cached message, skipping")
+		var stale []any
+		for i, value := range values {
+			data, ok := value.(string)
+			if !ok {
+				stale = append(stale, members[i])
 				continue
 			}
 
 			var cacheData models.SentMessageCache
-			if err := json.Unmarshal([]byte(result), &cacheData); err != nil {
-				r.logger.WithError(err).WithField("key", key).Warn("Failed to unmarshal cache data, skipping")
+			if err := json.Unmarshal([]byte(data), &cacheData); err != nil {
+				r.logger.WithError(err).WithField("key", keys[i]).Warn("Failed to unmarshal cache data, skipping")
 				continue
 			}
-
 			messages = append(messages, cacheData)
 		}
 
-		cursor = nextCursor
-		if cursor == 0 || len(messages) >= limit {
+		if len(stale) > 0 {
+			if err := r.redis.Client().ZRem(ctx, sentMessageIndexKey, stale...).Err(); err != nil {
+				r.logger.WithError(err).Warn("Failed to remove expired members from sent message index")
+				// skip past them, they are removed by a later read or trim
+				offset += int64(len(stale))
+			}
+		}
+		offset += int64(len(members) - len(stale))
+
+		if int64(len(members)) < want {
 			break
 		}
 	}
@@ -18,6 +18,7 @@ var _ = Describe("MessageCacheRepository", func() {
 			err = mockRedis.Client().Del(ctx, keys...).Err()
 			Expect(err).NotTo(HaveOccurred())
 		}
+		Expect(mockRedis.Client().Del(ctx, "sent_messages:index").Err()).To(Succeed())
 	})
 
 	AfterEach(func() {
@@ -208,11 +209,63 @@ var _ = Describe("MessageCacheRepository", func() {
 		})
 
 		Context("when limit is 1", func() {
-			It("should return only one message", func() {
+			It("should return only the most recently sent message", func() {
 				messages, err := messageCacheRepository.GetAllSentMessages(ctx, 1)
 
 				Expect(err).NotTo(HaveOccurred())
 				Expect(messages).To(HaveLen(1))
+				Expect(messages[0].MessageID).To(Equal(int64(3)))
+			})
+		})
+
+		Context("when ordering the cached messages", func() {
+			It("should return the most recently sent messages first", func() {
+				messages, err := messageCacheRepository.GetAllSentMessages(ctx, 10)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(messages).To(HaveLen(3))
+				Expect(messages[0].MessageID).To(Equal(int64(3)))
+				Expect(messages[1].MessageID).To(Equal(int64(2)))
+				Expect(messages[2].MessageID).To(Equal(int64(1)))
+			})
+		})
+
+		Context("when an entry expired before its index member", func() {
+			It("should skip it, fill the page with older entries and remove the member", func() {
+				err := mockRedis.Client().Del(ctx, "sent_message:3").Err()
+				Expect(err).NotTo(HaveOccurred())
+
+				messages, err := messageCacheRepository.GetAllSentMessages(ctx, 2)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(messages).To(HaveLen(2))
+				Expect(messages[0].MessageID).To(Equal(int64(2)))
+				Expect(messages[1].MessageID).To(Equal(int64(1)))
+
+				members, err := mockRedis.Client().ZRange(ctx, "sent_messages:index", 0, -1).Result()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(members).To(ConsistOf("1", "2"))
+			})
+		})
+
+		Context("when messages were sent longer than the TTL ago", func() {
+			It("should trim them from the index", func() {
+				err := messageCacheRepository.CacheSentMessage(ctx, models.SentMessageCache{
+					MessageID:         4,
+					ExternalMessageID: "ext-4",
+					To:                "+905554444444",
+					Content:           "Message 4",
+					SentAt:            time.Now().Add(-cacheTTL - time.Minute),
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				messages, err := messageCacheRepository.GetAllSentMessages(ctx, 10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(messages).To(HaveLen(3))
+
+				score, err := mockRedis.Client().ZScore(ctx, "sent_messages:index", "4").Result()
+				Expect(err).To(HaveOccurred())
+				Expect(score).To(BeZero())
 			})
 		})
 	})
@@ -1,9 +1,11 @@
 package repository_test
 
 import (
+	"strings"
 	"time"
 
 	"go-template-microservice/internal/models"
+	"go-template-microservice/internal/repository"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -23,7 +25,7 @@ var _ = Describe("MessageRepository", func() {
 	Describe("CreateMessage", func() {
 		Context("when creating a valid message", func() {
 			It("should create the message successfully", func() {
-				msg, err := messageRepository.CreateMessage("+905551234567", "Hello World")
+				msg, err := messageRepository.CreateMessage(models.Message{To: "+905551234567", Content: "Hello World"})
 
 				Expect(err).NotTo(HaveOccurred())
 				Expect(msg).NotTo(BeNil())
@@ -31,6 +33,7 @@ var _ = Describe("MessageRepository", func() {
 				Expect(msg.To).To(Equal("+905551234567"))
 				Expect(msg.Content).To(Equal("Hello World"))
 				Expect(msg.Status).To(Equal(models.StatusPending))
+				Expect(msg.Priority).To(Equal(models.PriorityNormal))
 				createdMessageID = msg.ID
 			})
 		})
@@ -42,7 +45,7 @@ var _ = Describe("MessageRepository", func() {
 					longContent += "a"
 				}
 
-				msg, err := messageRepository.CreateMessage("+905551234567", longContent)
+				msg, err := messageRepository.CreateMessage(models.Message{To: "+905551234567", Content: longContent})
 
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("content exceeds 160 character limit"))
@@ -57,7 +60,7 @@ var _ = Describe("MessageRepository", func() {
 					content += "a"
 				}
 
-				msg, err := messageRepository.CreateMessage("+905551234567", content)
+				msg, err := messageRepository.CreateMessage(models.Message{To: "+905551234567", Content: content})
 
 				Expect(err).NotTo(HaveOccurred())
 				Expect(msg).NotTo(BeNil())
@@ -66,16 +69,79 @@ var _ = Describe("MessageRepository", func() {
 		})
 	})
 
+	Describe("CreateMessages", func() {
+		Context("when creating a valid batch", func() {
+			It("should create all messages in order with PENDING status", func() {
+				created, err := messageRepository.CreateMessages([]models.Message{
+					{To: "+905551111111", Content: "Batch 1"},
+					{To: "+905552222222", Content: "Batch 2"},
+					{To: "+905553333333", Content: "Batch 3"},
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(created).To(HaveLen(3))
+				for i, msg := range created {
+					Expect(msg.ID).To(BeNumerically(">", 0))
+					Expect(msg.Status).To(Equal(models.StatusPending))
+					if i > 0 {
+						Expect(msg.ID).To(BeNumerically(">", created[i-1].ID))
+					}
+				}
+				Expect(created[1].To).To(Equal("+905552222222"))
+
+				pending, err := messageRepository.GetUnsentMessages(10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pending).To(HaveLen(3))
+			})
+		})
+
+		Context("when a message has multibyte content within 160 characters", func() {
+			It("should count characters rather than bytes", func() {
+				content := strings.Repeat("ğ", 100) + strings.Repeat("🚀", 60)
+
+				created, err := messageRepository.CreateMessages([]models.Message{
+					{To: "+905551111111", Content: content},
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(created).To(HaveLen(1))
+				Expect(created[0].Content).To(Equal(content))
+			})
+		})
+
+		Context("when one message exceeds 160 characters", func() {
+			It("should not create any message", func() {
+				longContent := ""
+				for i := 0; i < 161; i++ {
+					longContent += "a"
+				}
+
+				created, err := messageRepository.CreateMessages([]models.Message{
+					{To: "+905551111111", Content: "Valid"},
+					{To: "+905552222222", Content: longContent},
+				})
+
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("index 1"))
+				Expect(created).To(BeNil())
+
+				pending, err := messageRepository.GetUnsentMessages(10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pending).To(BeEmpty())
+			})
+		})
+	})
+
 	Describe("GetUnsentMessages", func() {
 		BeforeEach(func() {
 			// Create some test messages
-			_, err := messageRepository.CreateMessage("+905551111111", "Message 1")
+			_, err := messageRepository.CreateMessage(models.Message{To: "+905551111111", Content: "Message 1"})
 			Expect(err).NotTo(HaveOccurred())
 
-			_, err = messageRepository.CreateMessage("+905552222222", "Message 2")
+			_, err = messageRepository.CreateMessage(models.Message{To: "+905552222222", Content: "Message 2"})
 			Expect(err).NotTo(HaveOccurred())
 
-			_, err = messageRepository.CreateMessage("+905553333333", "Message 3")
+			_, err = messageRepository.CreateMessage(models.Message{To: "+905553333333", Content: "Message 3"})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -108,6 +174,57 @@ var _ = Describe("MessageRepository", func() {
 			})
 		})
 
+		Context("when messages are scheduled", func() {
+			It("should skip future messages and order the rest by due time", func() {
+				past := time.Now().Add(-1 * time.Hour)
+				_, err := messageRepository.CreateMessage(models.Message{To: "+905554444444", Content: "Scheduled in the past", ScheduledAt: &past})
+				Expect(err).NotTo(HaveOccurred())
+
+				future := time.Now().Add(1 * time.Hour)
+				_, err = messageRepository.CreateMessage(models.Message{To: "+905555555555", Content: "Scheduled tomorrow", ScheduledAt: &future})
+				Expect(err).NotTo(HaveOccurred())
+
+				messages, err := messageRepository.GetUnsentMessages(10)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(messages).To(HaveLen(4))
+				Expect(messages[0].Content).To(Equal("Scheduled in the past"))
+				Expect(messages[0].ScheduledAt).NotTo(BeNil())
+				Expect(messages[1].Content).To(Equal("Message 1"))
+			})
+
+			It("should compare scheduled times given in another timezone correctly", func() {
+				zone := time.FixedZone("UTC+5", 5*60*60)
+				due := time.Now().Add(-1 * time.Minute).In(zone)
+				_, err := messageRepository.CreateMessage(models.Message{To: "+905554444444", Content: "Due in UTC+5", ScheduledAt: &due})
+				Expect(err).NotTo(HaveOccurred())
+
+				notDue := time.Now().Add(1 * time.Minute).In(time.FixedZone("UTC-5", -5*60*60))
+				_, err = messageRepository.CreateMessage(models.Message{To: "+905555555555", Content: "Not due in UTC-5", ScheduledAt: &notDue})
+				Expect(err).NotTo(HaveOccurred())
+
+				messages, err := messageRepository.GetUnsentMessages(10)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(messages).To(HaveLen(4))
+				Expect(messages[0].Content).To(Equal("Due in UTC+5"))
+			})
+
+			It("should not claim messages before their scheduled time", func() {
+				_, err := mockSqlite.Database().Exec("DELETE FROM messages")
+				Expect(err).NotTo(HaveOccurred())
+
+				future := time.Now().Add(1 * time.Hour)
+				_, err = messageRepository.CreateMessage(models.Message{To: "+905555555555", Content: "Scheduled tomorrow", ScheduledAt: &future})
+				Expect(err).NotTo(HaveOccurred())
+
+				claimed, err := messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "worker-a", Limit: 10, LeaseDuration: time.Minute})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(claimed).To(BeEmpty())
+			})
+		})
+
 		Context("when there are no pending messages", func() {
 			BeforeEach(func() {
 				// Delete all messages
@@ -126,9 +243,12 @@ var _ = Describe("MessageRepository", func() {
 
 	Describe("UpdateMessageStatus", func() {
 		BeforeEach(func() {
-			msg, err := messageRepository.CreateMessage("+905551234567", "Test Message")
+			msg, err := messageRepository.CreateMessage(models.Message{To: "+905551234567", Content: "Test Message"})
 			Expect(err).NotTo(HaveOccurred())
 			createdMessageID = msg.ID
+
+			_, err = messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "worker-a", Limit: 1, LeaseDuration: time.Minute})
+			Expect(err).NotTo(HaveOccurred())
 		})
 
 		Context("when updating to SENT status", func() {
@@ -136,7 +256,7 @@ var _ = Describe("MessageRepository", func() {
 				externalID := "ext-123456"
 				sentAt := time.Now()
 
-				err := messageRepository.UpdateMessageStatus(createdMessageID, models.StatusSent, &externalID, &sentAt)
+				err := messageRepository.UpdateMessageStatus(createdMessageID, "worker-a", models.StatusSent, &externalID, &sentAt)
 
 				Expect(err).NotTo(HaveOccurred())
 
@@ -149,7 +269,7 @@ var _ = Describe("MessageRepository", func() {
 
 		Context("when updating to FAILED status", func() {
 			It("should update the message successfully", func() {
-				err := messageRepository.UpdateMessageStatus(createdMessageID, models.StatusFailed, nil, nil)
+				err := messageRepository.UpdateMessageStatus(createdMessageID, "worker-a", models.StatusFailed, nil, nil)
 
 				Expect(err).NotTo(HaveOccurred())
 
@@ -164,33 +284,504 @@ var _ = Describe("MessageRepository", func() {
 			It("should return an error", func() {
 				nonExistentID := int64(99999)
 
-				err := messageRepository.UpdateMessageStatus(nonExistentID, models.StatusSent, nil, nil)
+				err := messageRepository.UpdateMessageStatus(nonExistentID, "worker-a", models.StatusSent, nil, nil)
 
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("no message found with ID"))
+				Expect(err).To(MatchError(repository.ErrLeaseLost))
+			})
+		})
+
+		Context("when the lease is held by another worker", func() {
+			It("should return ErrLeaseLost and leave the message alone", func() {
+				err := messageRepository.UpdateMessageStatus(createdMessageID, "worker-b", models.StatusExpired, nil, nil)
+				Expect(err).To(MatchError(repository.ErrLeaseLost))
+
+				msg, err := messageRepository.GetMessage(createdMessageID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(msg.Status).To(Equal(models.StatusSending))
+				Expect(msg.LeaseOwner).To(Equal("worker-a"))
+			})
+		})
+	})
+
+	Describe("MarkMessageSent", func() {
+		BeforeEach(func() {
+			msg, err := messageRepository.CreateMessage(models.Message{To: "+905551234567", Content: "Test Message"})
+			Expect(err).NotTo(HaveOccurred())
+			createdMessageID = msg.ID
+
+			_, err = messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "worker-a", Limit: 1, LeaseDuration: time.Minute})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should record the external message ID and the endpoint", func() {
+			sentAt := time.Now()
+
+			err := messageRepository.MarkMessageSent(createdMessageID, "worker-a", "ext-123456", "http://gateway-b/webhook", sentAt)
+			Expect(err).NotTo(HaveOccurred())
+
+			msg, err := messageRepository.GetMessage(createdMessageID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msg.Status).To(Equal(models.StatusSent))
+			Expect(msg.ExternalMessageID).To(Equal("ext-123456"))
+			Expect(msg.Endpoint).To(Equal("http://gateway-b/webhook"))
+			Expect(msg.SentAt).To(BeTemporally("~", sentAt, time.Second))
+		})
+
+		It("should return an error when the message does not exist", func() {
+			err := messageRepository.MarkMessageSent(99999, "worker-a", "ext-123456", "http://gateway-b/webhook", time.Now())
+
+			Expect(err).To(MatchError(repository.ErrLeaseLost))
+		})
+
+		It("should not let a worker whose lease expired overwrite the next owner's claim", func() {
+			_, err := mockSqlite.Database().Exec("UPDATE messages SET lease_expires_at = ? WHERE id = ?", time.Now().Add(-time.Second), createdMessageID)
+			Expect(err).NotTo(HaveOccurred())
+			released, err := messageRepository.ReleaseExpiredLeases()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(released).To(BeEquivalentTo(1))
+			claimed, err := messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "worker-b", Limit: 1, LeaseDuration: time.Minute})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claimed).To(HaveLen(1))
+
+			err = messageRepository.MarkMessageSent(createdMessageID, "worker-a", "ext-stale", "http://gateway-a/webhook", time.Now())
+			Expect(err).To(MatchError(repository.ErrLeaseLost))
+
+			msg, err := messageRepository.GetMessage(createdMessageID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msg.Status).To(Equal(models.StatusSending))
+			Expect(msg.LeaseOwner).To(Equal("worker-b"))
+			Expect(msg.ExternalMessageID).To(BeEmpty())
+		})
+	})
+
+	Describe("RecordSendFailure", func() {
+		BeforeEach(func() {
+			msg, err := messageRepository.CreateMessage(models.Message{To: "+905551234567", Content: "Retry Message"})
+			Expect(err).NotTo(HaveOccurred())
+			createdMessageID = msg.ID
+
+			_, err = messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "worker-a", Limit: 1, LeaseDuration: time.Minute})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when a retry is scheduled in the future", func() {
+			It("should keep the message out of the unsent list until it is due", func() {
+				nextAttemptAt := time.Now().Add(1 * time.Hour)
+
+				err := messageRepository.RecordSendFailure(createdMessageID, "worker-a", models.StatusPending, "gateway timeout", &nextAttemptAt)
+				Expect(err).NotTo(HaveOccurred())
+
+				messages, err := messageRepository.GetUnsentMessages(10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(messages).To(BeEmpty())
+			})
+		})
+
+		Context("when the retry is already due", func() {
+			It("should return the message with its attempt details", func() {
+				nextAttemptAt := time.Now().Add(-1 * time.Second)
+
+				err := messageRepository.RecordSendFailure(createdMessageID, "worker-a", models.StatusPending, "gateway timeout", &nextAttemptAt)
+				Expect(err).NotTo(HaveOccurred())
+
+				messages, err := messageRepository.GetUnsentMessages(10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(messages).To(HaveLen(1))
+				Expect(messages[0].Attempts).To(Equal(1))
+				Expect(messages[0].LastError).To(Equal("gateway timeout"))
+				Expect(messages[0].NextAttemptAt).NotTo(BeNil())
+			})
+		})
+
+		Context("when retries are exhausted", func() {
+			It("should mark the message as FAILED", func() {
+				err := messageRepository.RecordSendFailure(createdMessageID, "worker-a", models.StatusFailed, "rejected", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				var status models.Status
+				var attempts int
+				err = mockSqlite.Database().QueryRow("SELECT status, attempts FROM messages WHERE id = ?", createdMessageID).Scan(&status, &attempts)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status).To(Equal(models.StatusFailed))
+				Expect(attempts).To(Equal(1))
+			})
+		})
+
+		Context("when message ID does not exist", func() {
+			It("should return an error", func() {
+				err := messageRepository.RecordSendFailure(99999, "worker-a", models.StatusFailed, "rejected", nil)
+
+				Expect(err).To(MatchError(repository.ErrLeaseLost))
 			})
 		})
 	})
 
+	Describe("ClaimMessages", func() {
+		BeforeEach(func() {
+			for _, content := range []string{"Claim 1", "Claim 2", "Claim 3"} {
+				_, err := messageRepository.CreateMessage(models.Message{To: "+905551234567", Content: content})
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		Context("when claiming pending messages", func() {
+			It("should move them to SENDING under the caller's lease, oldest first", func() {
+				claimed, err := messageRepository.ClaimMessages(repository.ClaimOptions{
+					Owner:         "worker-a",
+					Limit:         2,
+					LeaseDuration: time.Minute,
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(claimed).To(HaveLen(2))
+				Expect(claimed[0].Content).To(Equal("Claim 1"))
+				Expect(claimed[1].Content).To(Equal("Claim 2"))
+				for _, msg := range claimed {
+					Expect(msg.Status).To(Equal(models.StatusSending))
+					Expect(msg.LeaseOwner).To(Equal("worker-a"))
+					Expect(msg.LeaseExpiresAt).NotTo(BeNil())
+					Expect(*msg.LeaseExpiresAt).To(BeTemporally("~", time.Now().Add(time.Minute), 5*time.Second))
+				}
+
+				pending, err := messageRepository.GetUnsentMessages(10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pending).To(HaveLen(1))
+			})
+
+			It("should never hand the same message to two workers", func() {
+				first, err := messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "worker-a", Limit: 2, LeaseDuration: time.Minute})
+				Expect(err).NotTo(HaveOccurred())
+
+				second, err := messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "worker-b", Limit: 10, LeaseDuration: time.Minute})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(first).To(HaveLen(2))
+				Expect(second).To(HaveLen(1))
+				Expect(second[0].Content).To(Equal("Claim 3"))
+				Expect(second[0].LeaseOwner).To(Equal("worker-b"))
+			})
+		})
+
+		Context("when claiming a single priority lane", func() {
+			It("should only claim messages of that priority", func() {
+				_, err := messageRepository.CreateMessage(models.Message{To: "+905551234567", Content: "Claim OTP", Priority: models.PriorityHigh})
+				Expect(err).NotTo(HaveOccurred())
+
+				claimed, err := messageRepository.ClaimMessages(repository.ClaimOptions{
+					Owner:         "worker-a",
+					Limit:         10,
+					LeaseDuration: time.Minute,
+					Priority:      models.PriorityHigh,
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(claimed).To(HaveLen(1))
+				Expect(claimed[0].Content).To(Equal("Claim OTP"))
+				Expect(claimed[0].Priority).To(Equal(models.PriorityHigh))
+
+				low, err := messageRepository.ClaimMessages(repository.ClaimOptions{
+					Owner:         "worker-a",
+					Limit:         10,
+					LeaseDuration: time.Minute,
+					Priority:      models.PriorityLow,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(low).To(BeEmpty())
+			})
+		})
+
+		Context("when claiming given categories", func() {
+			It("should only claim messages of those categories", func() {
+				_, err := messageRepository.CreateMessage(models.Message{To: "+905551234567", Content: "Claim OTP", Category: "otp"})
+				Expect(err).NotTo(HaveOccurred())
+
+				claimed, err := messageRepository.ClaimMessages(repository.ClaimOptions{
+					Owner:         "worker-a",
+					Limit:         10,
+					LeaseDuration: time.Minute,
+					Categories:    []string{"otp", "alert"},
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(claimed).To(HaveLen(1))
+				Expect(claimed[0].Content).To(Equal("Claim OTP"))
+				Expect(claimed[0].Category).To(Equal("otp"))
+			})
+		})
+
+		Context("when a message is completed", func() {
+			It("should clear the lease", func() {
+				claimed, err := messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "worker-a", Limit: 1, LeaseDuration: time.Minute})
+				Expect(err).NotTo(HaveOccurred())
+
+				extID := "ext-claimed"
+				sentAt := time.Now()
+				err = messageRepository.UpdateMessageStatus(claimed[0].ID, "worker-a", models.StatusSent, &extID, &sentAt)
+				Expect(err).NotTo(HaveOccurred())
+
+				sent, err := messageRepository.GetSentMessages(10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sent).To(HaveLen(1))
+				Expect(sent[0].LeaseOwner).To(BeEmpty())
+				Expect(sent[0].LeaseExpiresAt).To(BeNil())
+			})
+		})
+	})
+
+	Describe("ReleaseClaims", func() {
+		It("should only release messages leased by the given owner", func() {
+			for _, content := range []string{"Release 1", "Release 2"} {
+				_, err := messageRepository.CreateMessage(models.Message{To: "+905551234567", Content: content})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			claimed, err := messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "worker-a", Limit: 2, LeaseDuration: time.Minute})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claimed).To(HaveLen(2))
+
+			err = messageRepository.ReleaseClaims("worker-b", []int64{claimed[0].ID})
+			Expect(err).NotTo(HaveOccurred())
+			pending, err := messageRepository.GetUnsentMessages(10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(BeEmpty())
+
+			err = messageRepository.ReleaseClaims("worker-a", []int64{claimed[0].ID, claimed[1].ID})
+			Expect(err).NotTo(HaveOccurred())
+			pending, err = messageRepository.GetUnsentMessages(10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(HaveLen(2))
+			Expect(pending[0].LeaseOwner).To(BeEmpty())
+		})
+	})
+
+	Describe("ReleaseExpiredLeases", func() {
+		It("should return messages with expired leases to PENDING", func() {
+			_, err := messageRepository.CreateMessage(models.Message{To: "+905551111111", Content: "Expired lease"})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "dead-worker", Limit: 1, LeaseDuration: -time.Second})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = messageRepository.CreateMessage(models.Message{To: "+905552222222", Content: "Active lease"})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "live-worker", Limit: 1, LeaseDuration: time.Hour})
+			Expect(err).NotTo(HaveOccurred())
+
+			released, err := messageRepository.ReleaseExpiredLeases()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(released).To(Equal(int64(1)))
+
+			pending, err := messageRepository.GetUnsentMessages(10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(HaveLen(1))
+			Expect(pending[0].Content).To(Equal("Expired lease"))
+			Expect(pending[0].Status).To(Equal(models.StatusPending))
+		})
+	})
+
+	Describe("ExpireMessages", func() {
+		It("should move PENDING messages past their expiry to EXPIRED", func() {
+			expiredAt := time.Now().Add(-1 * time.Minute)
+			stale, err := messageRepository.CreateMessage(models.Message{To: "+905551111111", Content: "Stale", ExpiresAt: &expiredAt})
+			Expect(err).NotTo(HaveOccurred())
+			validUntil := time.Now().Add(1 * time.Hour)
+			_, err = messageRepository.CreateMessage(models.Message{To: "+905552222222", Content: "Fresh", ExpiresAt: &validUntil})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = messageRepository.CreateMessage(models.Message{To: "+905553333333", Content: "No expiry"})
+			Expect(err).NotTo(HaveOccurred())
+
+			expired, err := messageRepository.ExpireMessages()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expired).To(Equal(int64(1)))
+
+			pending, err := messageRepository.GetUnsentMessages(10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(HaveLen(2))
+			for _, msg := range pending {
+				Expect(msg.ID).NotTo(Equal(stale.ID))
+			}
+			Expect(pending[0].ExpiresAt).NotTo(BeNil())
+			Expect(pending[0].ExpiresAt.Equal(validUntil)).To(BeTrue())
+		})
+	})
+
+	Describe("Single Message Operations", func() {
+		var msg *models.Message
+
+		BeforeEach(func() {
+			var err error
+			msg, err = messageRepository.CreateMessage(models.Message{To: "+905551234567", Content: "Editable"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when getting a message", func() {
+			It("should return the stored message", func() {
+				found, err := messageRepository.GetMessage(msg.ID)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found.Content).To(Equal("Editable"))
+				Expect(found.UpdatedAt.Equal(msg.UpdatedAt)).To(BeTrue())
+			})
+
+			It("should return ErrMessageNotFound for an unknown ID", func() {
+				_, err := messageRepository.GetMessage(msg.ID + 100)
+				Expect(err).To(MatchError(repository.ErrMessageNotFound))
+			})
+		})
+
+		Context("when updating a pending message", func() {
+			It("should change only the given fields and bump updated_at", func() {
+				content := "Edited"
+				updated, err := messageRepository.UpdatePendingMessage(msg.ID, msg.UpdatedAt, repository.MessageChanges{Content: &content})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updated.Content).To(Equal("Edited"))
+				Expect(updated.To).To(Equal("+905551234567"))
+				Expect(updated.UpdatedAt.After(msg.UpdatedAt)).To(BeTrue())
+			})
+
+			It("should refuse the update when the message was modified since it was read", func() {
+				to := "+905559999999"
+				_, err := messageRepository.UpdatePendingMessage(msg.ID, msg.UpdatedAt, repository.MessageChanges{To: &to})
+				Expect(err).NotTo(HaveOccurred())
+
+				content := "Stale edit"
+				_, err = messageRepository.UpdatePendingMessage(msg.ID, msg.UpdatedAt, repository.MessageChanges{Content: &content})
+				Expect(err).To(MatchError(repository.ErrMessageModified))
+			})
+		})
+
+		Context("when cancelling a message", func() {
+			It("should move a pending message to CANCELLED", func() {
+				cancelled, err := messageRepository.CancelMessage(msg.ID, msg.UpdatedAt)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cancelled.Status).To(Equal(models.StatusCancelled))
+
+				pending, err := messageRepository.GetUnsentMessages(10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pending).To(BeEmpty())
+			})
+
+			It("should refuse messages that are being sent", func() {
+				claimed, err := messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "worker-a", Limit: 1, LeaseDuration: time.Minute})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(claimed).To(HaveLen(1))
+
+				_, err = messageRepository.CancelMessage(msg.ID, claimed[0].UpdatedAt)
+				Expect(err).To(MatchError(repository.ErrMessageNotPending))
+			})
+
+			It("should return ErrMessageNotFound for an unknown ID", func() {
+				_, err := messageRepository.CancelMessage(msg.ID+100, msg.UpdatedAt)
+				Expect(err).To(MatchError(repository.ErrMessageNotFound))
+			})
+		})
+	})
+
+	Describe("ListMessages", func() {
+		var ids []int64
+
+		BeforeEach(func() {
+			ids = nil
+			for _, msg := range []models.Message{
+				{To: "+905551111111", Content: "Promo 50% off"},
+				{To: "+905552222222", Content: "Your code is 1234"},
+				{To: "+905551111111", Content: "Promo 5_0 off"},
+				{To: "+905553333333", Content: "Reminder"},
+			} {
+				created, err := messageRepository.CreateMessage(msg)
+				Expect(err).NotTo(HaveOccurred())
+				ids = append(ids, created.ID)
+			}
+
+			_, err := messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "worker-a", Limit: len(ids), LeaseDuration: time.Minute})
+			Expect(err).NotTo(HaveOccurred())
+			extID := "ext-list"
+			sentAt := time.Now()
+			Expect(messageRepository.UpdateMessageStatus(ids[1], "worker-a", models.StatusSent, &extID, &sentAt)).To(Succeed())
+			Expect(messageRepository.ReleaseClaims("worker-a", ids)).To(Succeed())
+		})
+
+		contents := func(messages []models.Message) []string {
+			var result []string
+			for _, msg := range messages {
+				result = append(result, msg.Content)
+			}
+			return result
+		}
+
+		It("should list newest first and continue after the cursor", func() {
+			first, err := messageRepository.ListMessages(repository.MessageFilter{Limit: 3})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents(first)).To(Equal([]string{"Reminder", "Promo 5_0 off", "Your code is 1234"}))
+
+			last := first[len(first)-1]
+			second, err := messageRepository.ListMessages(repository.MessageFilter{
+				Limit: 3,
+				After: &repository.MessageCursor{Time: last.CreatedAt, ID: last.ID},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents(second)).To(Equal([]string{"Promo 50% off"}))
+		})
+
+		It("should filter by status, recipient and content substring", func() {
+			sent, err := messageRepository.ListMessages(repository.MessageFilter{Statuses: []models.Status{models.StatusSent, models.StatusFailed}, Limit: 10})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents(sent)).To(Equal([]string{"Your code is 1234"}))
+
+			byRecipient, err := messageRepository.ListMessages(repository.MessageFilter{To: "+905551111111", Limit: 10})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(byRecipient).To(HaveLen(2))
+
+			// wildcards in the substring match literally
+			percent, err := messageRepository.ListMessages(repository.MessageFilter{Content: "50%", Limit: 10})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents(percent)).To(Equal([]string{"Promo 50% off"}))
+
+			underscore, err := messageRepository.ListMessages(repository.MessageFilter{Content: "5_", Limit: 10})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents(underscore)).To(Equal([]string{"Promo 5_0 off"}))
+		})
+
+		It("should filter by time ranges and only list sent messages when sorting by sent time", func() {
+			future := time.Now().Add(1 * time.Hour)
+			none, err := messageRepository.ListMessages(repository.MessageFilter{CreatedFrom: &future, Limit: 10})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(none).To(BeEmpty())
+
+			past := time.Now().Add(-1 * time.Hour)
+			all, err := messageRepository.ListMessages(repository.MessageFilter{CreatedFrom: &past, CreatedTo: &future, Limit: 10})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(all).To(HaveLen(4))
+
+			bySentAt, err := messageRepository.ListMessages(repository.MessageFilter{SortBy: repository.SortBySentAt, SentFrom: &past, Limit: 10})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents(bySentAt)).To(Equal([]string{"Your code is 1234"}))
+		})
+	})
+
 	Describe("GetSentMessages", func() {
 		BeforeEach(func() {
 			// Create and update messages to SENT status
-			msg1, err := messageRepository.CreateMessage("+905551111111", "Sent Message 1")
+			msg1, err := messageRepository.CreateMessage(models.Message{To: "+905551111111", Content: "Sent Message 1"})
 			Expect(err).NotTo(HaveOccurred())
 			extID1 := "ext-1"
 			sentAt1 := time.Now().Add(-2 * time.Hour)
-			err = messageRepository.UpdateMessageStatus(msg1.ID, models.StatusSent, &extID1, &sentAt1)
+			_, err = messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "worker-a", Limit: 1, LeaseDuration: time.Minute})
+			Expect(err).NotTo(HaveOccurred())
+			err = messageRepository.UpdateMessageStatus(msg1.ID, "worker-a", models.StatusSent, &extID1, &sentAt1)
 			Expect(err).NotTo(HaveOccurred())
 
-			msg2, err := messageRepository.CreateMessage("+905552222222", "Sent Message 2")
+			msg2, err := messageRepository.CreateMessage(models.Message{To: "+905552222222", Content: "Sent Message 2"})
 			Expect(err).NotTo(HaveOccurred())
 			extID2 := "ext-2"
 			sentAt2 := time.Now().Add(-1 * time.Hour)
-			err = messageRepository.UpdateMessageStatus(msg2.ID, models.StatusSent, &extID2, &sentAt2)
+			_, err = messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "worker-a", Limit: 1, LeaseDuration: time.Minute})
+			Expect(err).NotTo(HaveOccurred())
+			err = messageRepository.UpdateMessageStatus(msg2.ID, "worker-a", models.StatusSent, &extID2, &sentAt2)
 			Expect(err).NotTo(HaveOccurred())
 
 			// Create a pending message (should not be returned)
-			_, err = messageRepository.CreateMessage("+905553333333", "Pending Message")
+			_, err = messageRepository.CreateMessage(models.Message{To: "+905553333333", Content: "Pending Message"})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -0,0 +1,79 @@
+package repository_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go-template-microservice/internal/models"
+	"go-template-microservice/internal/repository"
+	"go-template-microservice/pkg/sqlite"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// baselineMessageSchema is the message table of the first release, before any column was added
+const baselineMessageSchema = `
+    CREATE TABLE IF NOT EXISTS messages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    "to" VARCHAR(20) NOT NULL,
+    content VARCHAR(160) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'PENDING',
+    external_message_id VARCHAR(64) NOT NULL,
+    sent_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_messages_status_created_at ON messages(status, created_at);
+`
+
+var _ = Describe("Message Schema Migration", func() {
+	var dbPath string
+
+	BeforeEach(func() {
+		dbPath = filepath.Join(os.TempDir(), "test_migration_message.db")
+		os.Remove(dbPath)
+
+		db, err := sql.Open("sqlite3", dbPath)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = db.Exec(baselineMessageSchema)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = db.Exec(`INSERT INTO messages ("to", content, external_message_id) VALUES ('+905551111111', 'Before upgrade', '')`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.Close()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.Remove(dbPath)
+	})
+
+	It("should upgrade a baseline database and keep its messages", func() {
+		db, err := sqlite.NewSqliteInstanceWithSchemas(dbPath, []sqlite.Schema{models.GetMessageSchema()})
+		Expect(err).NotTo(HaveOccurred())
+		defer db.Close()
+
+		repo := repository.NewMessageRepository(db, logger)
+		claimed, err := repo.ClaimMessages(repository.ClaimOptions{Owner: "migration-worker", Limit: 10, LeaseDuration: time.Minute, Priority: models.PriorityNormal})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claimed).To(HaveLen(1))
+		Expect(claimed[0].Content).To(Equal("Before upgrade"))
+		Expect(claimed[0].Attempts).To(BeZero())
+		Expect(claimed[0].Category).To(BeEmpty())
+
+		Expect(repo.MarkMessageSent(claimed[0].ID, "migration-worker", "ext-1", "http://gateway/webhook", time.Now())).To(Succeed())
+		msg, err := repo.GetMessage(claimed[0].ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(msg.Endpoint).To(Equal("http://gateway/webhook"))
+	})
+
+	It("should be a no-op on an up to date database", func() {
+		for i := 0; i < 2; i++ {
+			db, err := sqlite.NewSqliteInstanceWithSchemas(dbPath, []sqlite.Schema{models.GetMessageSchema()})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(db.Close()).To(Succeed())
+		}
+	})
+})
@@ -0,0 +1,101 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/repository/leader_lease.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/repository/leader_lease.go -destination=./internal/repository/mocks/leader_lease_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLeaderLeaseRepository is a mock of LeaderLeaseRepository interface.
+type MockLeaderLeaseRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLeaderLeaseRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockLeaderLeaseRepositoryMockRecorder is the mock recorder for MockLeaderLeaseRepository.
+type MockLeaderLeaseRepositoryMockRecorder struct {
+	mock *MockLeaderLeaseRepository
+}
+
+// NewMockLeaderLeaseRepository creates a new mock instance.
+func NewMockLeaderLeaseRepository(ctrl *gomock.Controller) *MockLeaderLeaseRepository {
+	mock := &MockLeaderLeaseRepository{ctrl: ctrl}
+	mock.recorder = &MockLeaderLeaseRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLeaderLeaseRepository) EXPECT() *MockLeaderLeaseRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Acquire mocks base method.
+func (m *MockLeaderLeaseRepository) Acquire(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Acquire", ctx, owner, ttl)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Acquire indicates an expected call of Acquire.
+func (mr *MockLeaderLeaseRepositoryMockRecorder) Acquire(ctx, owner, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Acquire", reflect.TypeOf((*MockLeaderLeaseRepository)(nil).Acquire), ctx, owner, ttl)
+}
+
+// GetLeader mocks base method.
+func (m *MockLeaderLeaseRepository) GetLeader(ctx context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLeader", ctx)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLeader indicates an expected call of GetLeader.
+func (mr *MockLeaderLeaseRepositoryMockRecorder) GetLeader(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLeader", reflect.TypeOf((*MockLeaderLeaseRepository)(nil).GetLeader), ctx)
+}
+
+// Release mocks base method.
+func (m *MockLeaderLeaseRepository) Release(ctx context.Context, owner string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, owner)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockLeaderLeaseRepositoryMockRecorder) Release(ctx, owner any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockLeaderLeaseRepository)(nil).Release), ctx, owner)
+}
+
+// Renew mocks base method.
+func (m *MockLeaderLeaseRepository) Renew(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Renew", ctx, owner, ttl)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Renew indicates an expected call of Renew.
+func (mr *MockLeaderLeaseRepositoryMockRecorder) Renew(ctx, owner, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Renew", reflect.TypeOf((*MockLeaderLeaseRepository)(nil).Renew), ctx, owner, ttl)
+}
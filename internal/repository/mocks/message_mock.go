@@ -3,7 +3,7 @@
 //
 // Generated by this command:
 //
-//	mockgen -source=./internal/repository/message.go -destination=./internal/repository/mocks/message_mock.go -package=mocks
+//	mockgen -source=./internal/repository/message.go -destination=./internal/repository/mocks/message_mock.go -package=mocks -exclude_interfaces=rowScanner
 //
 
 // Package mocks is a generated GoMock package.
@@ -11,6 +11,7 @@ package mocks
 
 import (
 	models "go-template-microservice/internal/models"
+	repository "go-template-microservice/internal/repository"
 	reflect "reflect"
 	time "time"
 
@@ -41,19 +42,94 @@ func (m *MockMessageRepository) EXPECT() *MockMessageRepositoryMockRecorder {
 	return m.recorder
 }
 
+// CancelMessage mocks base method.
+func (m *MockMessageRepository) CancelMessage(messageID int64, updatedAt time.Time) (*models.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelMessage", messageID, updatedAt)
+	ret0, _ := ret[0].(*models.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelMessage indicates an expected call of CancelMessage.
+func (mr *MockMessageRepositoryMockRecorder) CancelMessage(messageID, updatedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelMessage", reflect.TypeOf((*MockMessageRepository)(nil).CancelMessage), messageID, updatedAt)
+}
+
+// ClaimMessages mocks base method.
+func (m *MockMessageRepository) ClaimMessages(opts repository.ClaimOptions) ([]models.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimMessages", opts)
+	ret0, _ := ret[0].([]models.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimMessages indicates an expected call of ClaimMessages.
+func (mr *MockMessageRepositoryMockRecorder) ClaimMessages(opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimMessages", reflect.TypeOf((*MockMessageRepository)(nil).ClaimMessages), opts)
+}
+
 // CreateMessage mocks base method.
-func (m *MockMessageRepository) CreateMessage(to, content string) (*models.Message, error) {
+func (m *MockMessageRepository) CreateMessage(message models.Message) (*models.Message, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateMessage", to, content)
+	ret := m.ctrl.Call(m, "CreateMessage", message)
 	ret0, _ := ret[0].(*models.Message)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateMessage indicates an expected call of CreateMessage.
-func (mr *MockMessageRepositoryMockRecorder) CreateMessage(to, content any) *gomock.Call {
+func (mr *MockMessageRepositoryMockRecorder) CreateMessage(message any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMessage", reflect.TypeOf((*MockMessageRepository)(nil).CreateMessage), to, content)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMessage", reflect.TypeOf((*MockMessageRepository)(nil).CreateMessage), message)
+}
+
+// CreateMessages mocks base method.
+func (m *MockMessageRepository) CreateMessages(messages []models.Message) ([]models.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateMessages", messages)
+	ret0, _ := ret[0].([]models.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateMessages indicates an expected call of CreateMessages.
+func (mr *MockMessageRepositoryMockRecorder) CreateMessages(messages any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMessages", reflect.TypeOf((*MockMessageRepository)(nil).CreateMessages), messages)
+}
+
+// ExpireMessages mocks base method.
+func (m *MockMessageRepository) ExpireMessages() (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireMessages")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireMessages indicates an expected call of ExpireMessages.
+func (mr *MockMessageRepositoryMockRecorder) ExpireMessages() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireMessages", reflect.TypeOf((*MockMessageRepository)(nil).ExpireMessages))
+}
+
+// GetMessage mocks base method.
+func (m *MockMessageRepository) GetMessage(messageID int64) (*models.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessage", messageID)
+	ret0, _ := ret[0].(*models.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessage indicates an expected call of GetMessage.
+func (mr *MockMessageRepositoryMockRecorder) GetMessage(messageID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessage", reflect.TypeOf((*MockMessageRepository)(nil).GetMessage), messageID)
 }
 
 // GetSentMessages mocks base method.
@@ -86,16 +162,103 @@ func (mr *MockMessageRepositoryMockRecorder) GetUnsentMessages(limit any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUnsentMessages", reflect.TypeOf((*MockMessageRepository)(nil).GetUnsentMessages), limit)
 }
 
+// ListMessages mocks base method.
+func (m *MockMessageRepository) ListMessages(filter repository.MessageFilter) ([]models.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMessages", filter)
+	ret0, _ := ret[0].([]models.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMessages indicates an expected call of ListMessages.
+func (mr *MockMessageRepositoryMockRecorder) ListMessages(filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMessages", reflect.TypeOf((*MockMessageRepository)(nil).ListMessages), filter)
+}
+
+// MarkMessageSent mocks base method.
+func (m *MockMessageRepository) MarkMessageSent(messageID int64, owner, externalMessageID, endpoint string, sentAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkMessageSent", messageID, owner, externalMessageID, endpoint, sentAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkMessageSent indicates an expected call of MarkMessageSent.
+func (mr *MockMessageRepositoryMockRecorder) MarkMessageSent(messageID, owner, externalMessageID, endpoint, sentAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkMessageSent", reflect.TypeOf((*MockMessageRepository)(nil).MarkMessageSent), messageID, owner, externalMessageID, endpoint, sentAt)
+}
+
+// RecordSendFailure mocks base method.
+func (m *MockMessageRepository) RecordSendFailure(messageID int64, owner string, status models.Status, lastError string, nextAttemptAt *time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordSendFailure", messageID, owner, status, lastError, nextAttemptAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordSendFailure indicates an expected call of RecordSendFailure.
+func (mr *MockMessageRepositoryMockRecorder) RecordSendFailure(messageID, owner, status, lastError, nextAttemptAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordSendFailure", reflect.TypeOf((*MockMessageRepository)(nil).RecordSendFailure), messageID, owner, status, lastError, nextAttemptAt)
+}
+
+// ReleaseClaims mocks base method.
+func (m *MockMessageRepository) ReleaseClaims(owner string, messageIDs []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseClaims", owner, messageIDs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseClaims indicates an expected call of ReleaseClaims.
+func (mr *MockMessageRepositoryMockRecorder) ReleaseClaims(owner, messageIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseClaims", reflect.TypeOf((*MockMessageRepository)(nil).ReleaseClaims), owner, messageIDs)
+}
+
+// ReleaseExpiredLeases mocks base method.
+func (m *MockMessageRepository) ReleaseExpiredLeases() (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseExpiredLeases")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseExpiredLeases indicates an expected call of ReleaseExpiredLeases.
+func (mr *MockMessageRepositoryMockRecorder) ReleaseExpiredLeases() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseExpiredLeases", reflect.TypeOf((*MockMessageRepository)(nil).ReleaseExpiredLeases))
+}
+
 // UpdateMessageStatus mocks base method.
-func (m *MockMessageRepository) UpdateMessageStatus(messageID int64, status models.Status, externalMessageID *string, sentAt *time.Time) error {
+func (m *MockMessageRepository) UpdateMessageStatus(messageID int64, owner string, status models.Status, externalMessageID *string, sentAt *time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateMessageStatus", messageID, status, externalMessageID, sentAt)
+	ret := m.ctrl.Call(m, "UpdateMessageStatus", messageID, owner, status, externalMessageID, sentAt)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateMessageStatus indicates an expected call of UpdateMessageStatus.
-func (mr *MockMessageRepositoryMockRecorder) UpdateMessageStatus(messageID, status, externalMessageID, sentAt any) *gomock.Call {
+func (mr *MockMessageRepositoryMockRecorder) UpdateMessageStatus(messageID, owner, status, externalMessageID, sentAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMessageStatus", reflect.TypeOf((*MockMessageRepository)(nil).UpdateMessageStatus), messageID, owner, status, externalMessageID, sentAt)
+}
+
+// UpdatePendingMessage mocks base method.
+func (m *MockMessageRepository) UpdatePendingMessage(messageID int64, updatedAt time.Time, changes repository.MessageChanges) (*models.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePendingMessage", messageID, updatedAt, changes)
+	ret0, _ := ret[0].(*models.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePendingMessage indicates an expected call of UpdatePendingMessage.
+func (mr *MockMessageRepositoryMockRecorder) UpdatePendingMessage(messageID, updatedAt, changes any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMessageStatus", reflect.TypeOf((*MockMessageRepository)(nil).UpdateMessageStatus), messageID, status, externalMessageID, sentAt)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePendingMessage", reflect.TypeOf((*MockMessageRepository)(nil).UpdatePendingMessage), messageID, updatedAt, changes)
 }
@@ -40,6 +40,20 @@ func (m *MockIRepository) EXPECT() *MockIRepositoryMockRecorder {
 	return m.recorder
 }
 
+// GetLeaderLeaseRepository mocks base method.
+func (m *MockIRepository) GetLeaderLeaseRepository() repository.LeaderLeaseRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLeaderLeaseRepository")
+	ret0, _ := ret[0].(repository.LeaderLeaseRepository)
+	return ret0
+}
+
+// GetLeaderLeaseRepository indicates an expected call of GetLeaderLeaseRepository.
+func (mr *MockIRepositoryMockRecorder) GetLeaderLeaseRepository() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLeaderLeaseRepository", reflect.TypeOf((*MockIRepository)(nil).GetLeaderLeaseRepository))
+}
+
 // GetMessageCacheRepository mocks base method.
 func (m *MockIRepository) GetMessageCacheRepository() repository.MessageCacheRepository {
 	m.ctrl.T.Helper()
@@ -67,3 +81,17 @@ func (mr *MockIRepositoryMockRecorder) GetMessageRepository() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessageRepository", reflect.TypeOf((*MockIRepository)(nil).GetMessageRepository))
 }
+
+// GetSchedulerStateRepository mocks base method.
+func (m *MockIRepository) GetSchedulerStateRepository() repository.SchedulerStateRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSchedulerStateRepository")
+	ret0, _ := ret[0].(repository.SchedulerStateRepository)
+	return ret0
+}
+
+// GetSchedulerStateRepository indicates an expected call of GetSchedulerStateRepository.
+func (mr *MockIRepositoryMockRecorder) GetSchedulerStateRepository() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedulerStateRepository", reflect.TypeOf((*MockIRepository)(nil).GetSchedulerStateRepository))
+}
@@ -0,0 +1,100 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/repository/scheduler_state.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/repository/scheduler_state.go -destination=./internal/repository/mocks/scheduler_state_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	models "go-template-microservice/internal/models"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSchedulerStateRepository is a mock of SchedulerStateRepository interface.
+type MockSchedulerStateRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSchedulerStateRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockSchedulerStateRepositoryMockRecorder is the mock recorder for MockSchedulerStateRepository.
+type MockSchedulerStateRepositoryMockRecorder struct {
+	mock *MockSchedulerStateRepository
+}
+
+// NewMockSchedulerStateRepository creates a new mock instance.
+func NewMockSchedulerStateRepository(ctrl *gomock.Controller) *MockSchedulerStateRepository {
+	mock := &MockSchedulerStateRepository{ctrl: ctrl}
+	mock.recorder = &MockSchedulerStateRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSchedulerStateRepository) EXPECT() *MockSchedulerStateRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetDesiredState mocks base method.
+func (m *MockSchedulerStateRepository) GetDesiredState(ctx context.Context) (models.SchedulerState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDesiredState", ctx)
+	ret0, _ := ret[0].(models.SchedulerState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDesiredState indicates an expected call of GetDesiredState.
+func (mr *MockSchedulerStateRepositoryMockRecorder) GetDesiredState(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDesiredState", reflect.TypeOf((*MockSchedulerStateRepository)(nil).GetDesiredState), ctx)
+}
+
+// GetSettings mocks base method.
+func (m *MockSchedulerStateRepository) GetSettings(ctx context.Context) (models.SchedulerSettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSettings", ctx)
+	ret0, _ := ret[0].(models.SchedulerSettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSettings indicates an expected call of GetSettings.
+func (mr *MockSchedulerStateRepositoryMockRecorder) GetSettings(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSettings", reflect.TypeOf((*MockSchedulerStateRepository)(nil).GetSettings), ctx)
+}
+
+// SetDesiredState mocks base method.
+func (m *MockSchedulerStateRepository) SetDesiredState(ctx context.Context, state models.SchedulerState) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDesiredState", ctx, state)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDesiredState indicates an expected call of SetDesiredState.
+func (mr *MockSchedulerStateRepositoryMockRecorder) SetDesiredState(ctx, state any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDesiredState", reflect.TypeOf((*MockSchedulerStateRepository)(nil).SetDesiredState), ctx, state)
+}
+
+// UpdateSettings mocks base method.
+func (m *MockSchedulerStateRepository) UpdateSettings(ctx context.Context, settings models.SchedulerSettings) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSettings", ctx, settings)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSettings indicates an expected call of UpdateSettings.
+func (mr *MockSchedulerStateRepositoryMockRecorder) UpdateSettings(ctx, settings any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSettings", reflect.TypeOf((*MockSchedulerStateRepository)(nil).UpdateSettings), ctx, settings)
+}
@@ -3,17 +3,28 @@ package repository
 type IRepository interface {
 	GetMessageRepository() MessageRepository
 	GetMessageCacheRepository() MessageCacheRepository
+	GetSchedulerStateRepository() SchedulerStateRepository
+	GetLeaderLeaseRepository() LeaderLeaseRepository
 }
 
 type repository struct {
-	messageRepo      MessageRepository
-	messageCacheRepo MessageCacheRepository
+	messageRepo        MessageRepository
+	messageCacheRepo   MessageCacheRepository
+	schedulerStateRepo SchedulerStateRepository
+	leaderLeaseRepo    LeaderLeaseRepository
 }
 
-func NewRepository(messageRepo MessageRepository, messageCacheRepo MessageCacheRepository) IRepository {
+func NewRepository(
+	messageRepo MessageRepository,
+	messageCacheRepo MessageCacheRepository,
+	schedulerStateRepo SchedulerStateRepository,
+	leaderLeaseRepo LeaderLeaseRepository,
+) IRepository {
 	return &repository{
-		messageRepo:      messageRepo,
-		messageCacheRepo: messageCacheRepo,
+		messageRepo:        messageRepo,
+		messageCacheRepo:   messageCacheRepo,
+		schedulerStateRepo: schedulerStateRepo,
+		leaderLeaseRepo:    leaderLeaseRepo,
 	}
 }
 
@@ -24,3 +35,11 @@ func (r *repository) GetMessageRepository() MessageRepository {
 func (r *repository) GetMessageCacheRepository() MessageCacheRepository {
 	return r.messageCacheRepo
 }
+
+func (r *repository) GetSchedulerStateRepository() SchedulerStateRepository {
+	return r.schedulerStateRepo
+}
+
+func (r *repository) GetLeaderLeaseRepository() LeaderLeaseRepository {
+	return r.leaderLeaseRepo
+}
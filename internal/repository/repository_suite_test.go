@@ -40,6 +40,8 @@ var (
 	repo                   repository.IRepository
 	messageRepository      repository.MessageRepository
 	messageCacheRepository repository.MessageCacheRepository
+	schedulerStateRepo     repository.SchedulerStateRepository
+	leaderLeaseRepo        repository.LeaderLeaseRepository
 	mockCtrl               *gomock.Controller
 	repoMock               *mocks.MockIRepository
 	messageMock            *mocks.MockMessageRepository
@@ -57,7 +59,7 @@ var _ = BeforeSuite(func() {
 	os.Remove(testDBPath)
 
 	// Initialize SQLite
-	mockSqlite, err = sqlite.NewSqliteInstanceWithSchemas(testDBPath, []string{models.GetMessageSchema()})
+	mockSqlite, err = sqlite.NewSqliteInstanceWithSchemas(testDBPath, []sqlite.Schema{models.GetMessageSchema()})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(mockSqlite).NotTo(BeNil())
 
@@ -69,7 +71,9 @@ var _ = BeforeSuite(func() {
 	// Create repository instances
 	messageRepository = repository.NewMessageRepository(mockSqlite, logger)
 	messageCacheRepository = repository.NewMessageCacheRepository(mockRedis, cacheTTL, logger)
-	repo = repository.NewRepository(messageRepository, messageCacheRepository)
+	schedulerStateRepo = repository.NewSchedulerStateRepository(mockRedis, logger)
+	leaderLeaseRepo = repository.NewLeaderLeaseRepository(mockRedis, logger)
+	repo = repository.NewRepository(messageRepository, messageCacheRepository, schedulerStateRepo, leaderLeaseRepo)
 })
 
 var _ = BeforeEach(func() {
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go-template-microservice/internal/models"
+	"go-template-microservice/pkg/redis"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+type SchedulerStateRepository interface {
+	// GetDesiredState returns the last saved scheduler state, empty when none was saved yet
+	GetDesiredState(ctx context.Context) (models.SchedulerState, error)
+	// SetDesiredState saves the scheduler state so it survives restarts and is shared across replicas
+	SetDesiredState(ctx context.Context, state models.SchedulerState) error
+	// GetSettings returns the scheduler settings saved so far, with nil fields when none were saved
+	GetSettings(ctx context.Context) (models.SchedulerSettings, error)
+	// UpdateSettings saves the non-nil settings and keeps the other ones, they are shared across
+	// replicas and survive restarts like the desired state
+	UpdateSettings(ctx context.Context, settings models.SchedulerSettings) error
+}
+
+type schedulerStateRepository struct {
+	redis  redis.IRedisInstance
+	logger *logrus.Logger
+}
+
+const (
+	// schedulerDesiredStateKey holds the desired scheduler state, it never expires
+	schedulerDesiredStateKey = "scheduler:desired_state"
+	// schedulerSettingsKey is a hash of the scheduler settings changed at runtime, it never expires
+	schedulerSettingsKey = "scheduler:settings"
+
+	settingsIntervalField    = "interval_in_seconds"
+	settingsBatchSizeField   = "batch_size"
+	settingsConcurrencyField = "concurrency"
+)
+
+func NewSchedulerStateRepository(redis redis.IRedisInstance, logger *logrus.Logger) SchedulerStateRepository {
+	return &schedulerStateRepository{
+		redis:  redis,
+		logger: logger,
+	}
+}
+
+func (r *schedulerStateRepository) GetDesiredState(ctx context.Context) (models.SchedulerState, error) {
+	state, err := r.redis.Client().Get(ctx, schedulerDesiredStateKey).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get desired scheduler state")
+		return "", fmt.Errorf("failed to get desired scheduler state: %w", err)
+	}
+
+	return models.SchedulerState(state), nil
+}
+
+func (r *schedulerStateRepository) SetDesiredState(ctx context.Context, state models.SchedulerState) error {
+	if err := r.redis.Client().Set(ctx, schedulerDesiredStateKey, string(state), 0).Err(); err != nil {
+		r.logger.WithError(err).WithField("state", state).Error("Failed to save desired scheduler state")
+		return fmt.Errorf("failed to save desired scheduler state: %w", err)
+	}
+
+	r.logger.WithField("state", state).Debug("Desired scheduler state saved")
+	return nil
+}
+
+func (r *schedulerStateRepository) GetSettings(ctx context.Context) (models.SchedulerSettings, error) {
+	fields, err := r.redis.Client().HGetAll(ctx, schedulerSettingsKey).Result()
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get scheduler settings")
+		return models.SchedulerSettings{}, fmt.Errorf("failed to get scheduler settings: %w", err)
+	}
+
+	var settings models.SchedulerSettings
+	for field, target := range map[string]**int{
+		settingsIntervalField:    &settings.IntervalInSeconds,
+		settingsBatchSizeField:   &settings.BatchSize,
+		settingsConcurrencyField: &settings.Concurrency,
+	} {
+		raw, ok := fields[field]
+		if !ok {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			// a broken value keeps its configured default instead of hiding the other settings
+			r.logger.WithError(err).WithField("field", field).Warn("Ignoring invalid scheduler setting")
+			continue
+		}
+		*target = &value
+	}
+	return settings, nil
+}
+
+func (r *schedulerStateRepository) UpdateSettings(ctx context.Context, settings models.SchedulerSettings) error {
+	var values []any
+	for field, value := range map[string]*int{
+		settingsIntervalField:    settings.IntervalInSeconds,
+		settingsBatchSizeField:   settings.BatchSize,
+		settingsConcurrencyField: settings.Concurrency,
+	} {
+		if value != nil {
+			values = append(values, field, *value)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	// a single HSET only touches the given fields, concurrent updates of other settings are kept
+	if err := r.redis.Client().HSet(ctx, schedulerSettingsKey, values...).Err(); err != nil {
+		r.logger.WithError(err).Error("Failed to save scheduler settings")
+		return fmt.Errorf("failed to save scheduler settings: %w", err)
+	}
+
+	r.logger.WithField("settings", values).Debug("Scheduler settings saved")
+	return nil
+}
@@ -0,0 +1,69 @@
+package repository_test
+
+import (
+	"go-template-microservice/internal/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SchedulerStateRepository", func() {
+	BeforeEach(func() {
+		err := mockRedis.Client().Del(ctx, "scheduler:desired_state", "scheduler:settings").Err()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("GetDesiredState", func() {
+		Context("when no state was saved", func() {
+			It("should return an empty state", func() {
+				state, err := schedulerStateRepo.GetDesiredState(ctx)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(state).To(BeEmpty())
+			})
+		})
+
+		Context("when a state was saved", func() {
+			It("should return the last saved state", func() {
+				Expect(schedulerStateRepo.SetDesiredState(ctx, models.SchedulerStateRunning)).To(Succeed())
+				Expect(schedulerStateRepo.SetDesiredState(ctx, models.SchedulerStateStopped)).To(Succeed())
+
+				state, err := schedulerStateRepo.GetDesiredState(ctx)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(state).To(Equal(models.SchedulerStateStopped))
+			})
+
+			It("should keep the state without expiry", func() {
+				Expect(repo.GetSchedulerStateRepository().SetDesiredState(ctx, models.SchedulerStateRunning)).To(Succeed())
+
+				ttl, err := mockRedis.Client().TTL(ctx, "scheduler:desired_state").Result()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ttl).To(BeNumerically("<", 0))
+			})
+		})
+	})
+
+	Describe("Settings", func() {
+		It("should return no settings when none were saved", func() {
+			settings, err := schedulerStateRepo.GetSettings(ctx)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settings).To(Equal(models.SchedulerSettings{}))
+		})
+
+		It("should only overwrite the given settings", func() {
+			interval, batchSize, concurrency := 30, 20, 4
+			Expect(schedulerStateRepo.UpdateSettings(ctx, models.SchedulerSettings{IntervalInSeconds: &interval, BatchSize: &batchSize})).To(Succeed())
+			batchSize = 50
+			Expect(schedulerStateRepo.UpdateSettings(ctx, models.SchedulerSettings{BatchSize: &batchSize, Concurrency: &concurrency})).To(Succeed())
+
+			settings, err := schedulerStateRepo.GetSettings(ctx)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settings.IntervalInSeconds).To(HaveValue(Equal(30)))
+			Expect(settings.BatchSize).To(HaveValue(Equal(50)))
+			Expect(settings.Concurrency).To(HaveValue(Equal(4)))
+		})
+	})
+})
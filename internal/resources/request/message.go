@@ -1,5 +1,60 @@
 package request
 
+import "time"
+
 type ListSentMessagesRequest struct {
 	Limit int `json:"limit" validate:"omitempty,gte=1,lte=1000" default:"10"`
 }
+
+// ListMessagesRequest filters and pages the message listing, times are RFC 3339 timestamps and
+// the ranges include their start and exclude their end
+type ListMessagesRequest struct {
+	Status      []string `query:"status" validate:"omitempty,dive,oneof=PENDING SENDING SENT FAILED EXPIRED CANCELLED"`
+	To          string   `query:"to" validate:"omitempty,max=20"`
+	Content     string   `query:"content" validate:"omitempty,max=160"`
+	CreatedFrom string   `query:"created_from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	CreatedTo   string   `query:"created_to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	SentFrom    string   `query:"sent_from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	SentTo      string   `query:"sent_to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Sort        string   `query:"sort" validate:"omitempty,oneof=created_at sent_at" default:"created_at"`
+	Cursor      string   `query:"cursor" validate:"omitempty,max=256"`
+	Limit       int      `query:"limit" validate:"omitempty,gte=1,lte=1000" default:"10"`
+}
+
+type CreateMessageRequest struct {
+	To          string     `json:"to" validate:"required,max=20"`
+	Content     string     `json:"content" validate:"required,max=160"`
+	ScheduledAt *time.Time `json:"scheduled_at" validate:"omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at" validate:"omitempty"`
+	Priority    string     `json:"priority" validate:"omitempty,oneof=high normal low" enums:"high,normal,low" default:"normal"`
+	Category    string     `json:"category" validate:"omitempty,max=32,lowercase" example:"otp"`
+}
+
+// UpdateMessageRequest edits a PENDING message, omitted fields keep their value. When UpdatedAt is
+// given the update is refused if the message was modified since.
+type UpdateMessageRequest struct {
+	To        *string    `json:"to" validate:"omitnil,min=1,max=20"`
+	Content   *string    `json:"content" validate:"omitnil,min=1,max=160"`
+	UpdatedAt *time.Time `json:"updated_at" validate:"omitempty"`
+}
+
+// CancelMessageRequest withdraws a PENDING message. When UpdatedAt, an RFC 3339 timestamp, is given
+// the cancellation is refused if the message was modified since.
+type CancelMessageRequest struct {
+	UpdatedAt string `query:"updated_at" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+type CreateMessagesBatchRequest struct {
+	Messages []CreateMessageRequest `json:"messages" validate:"required,min=1,max=1000"`
+}
+
+type DispatchMessagesRequest struct {
+	BatchSize int `json:"batch_size" validate:"omitempty,min=1,max=1000"`
+}
+
+// UpdateSchedulerRequest changes the scheduler settings at runtime, omitted fields keep their value
+type UpdateSchedulerRequest struct {
+	IntervalInSeconds *int `json:"interval_in_seconds" validate:"omitempty,min=1,max=86400"`
+	BatchSize         *int `json:"batch_size" validate:"omitempty,min=1,max=1000"`
+	Concurrency       *int `json:"concurrency" validate:"omitempty,min=1,max=100"`
+}
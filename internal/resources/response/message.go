@@ -1,5 +1,10 @@
 package response
 
+import (
+	"go-template-microservice/internal/models"
+	"time"
+)
+
 type SentMessagesResponse struct {
 	Status    string                `json:"status"`
 	Timestamp int64                 `json:"timestamp"`
@@ -13,3 +18,101 @@ type SentMessageResponse struct {
 	Content           string `json:"content"`
 	SentAt            string `json:"sent_at"`
 }
+
+type MessageResponse struct {
+	Status    string         `json:"status"`
+	Timestamp int64          `json:"timestamp"`
+	Data      models.Message `json:"data"`
+}
+
+// CursorPagination describes a page of a keyset paginated listing, NextCursor fetches the
+// following page and is empty on the last one
+type CursorPagination struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+type MessagesPaginationResponse struct {
+	Status     string           `json:"status"`
+	Timestamp  int64            `json:"timestamp"`
+	Data       []models.Message `json:"data"`
+	Pagination CursorPagination `json:"pagination"`
+}
+
+const (
+	BatchItemCreated  = "created"
+	BatchItemRejected = "rejected"
+)
+
+type BatchMessageResult struct {
+	Index  int               `json:"index"`
+	ID     int64             `json:"id,omitempty"`
+	Status string            `json:"status"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+type BatchMessagesResult struct {
+	Created  int                  `json:"created"`
+	Rejected int                  `json:"rejected"`
+	Results  []BatchMessageResult `json:"results"`
+}
+
+type BatchMessagesResponse struct {
+	Status    string              `json:"status"`
+	Timestamp int64               `json:"timestamp"`
+	Data      BatchMessagesResult `json:"data"`
+}
+
+// SchedulerTickStats counts the delivery outcomes of one or more scheduler ticks
+type SchedulerTickStats struct {
+	Sent    int `json:"sent"`
+	Retried int `json:"retried"`
+	Failed  int `json:"failed"`
+	// Skipped messages were claimed but returned to the queue, e.g. after a 429 or on shutdown
+	Skipped int `json:"skipped"`
+	// Expired messages passed their expiry before they were sent and were not sent at all
+	Expired int `json:"expired"`
+}
+
+type SchedulerStatus struct {
+	Running            bool       `json:"running"`
+	Instance           string     `json:"instance,omitempty"`
+	Leader             string     `json:"leader,omitempty"`
+	StartedAt          *time.Time `json:"started_at,omitempty"`
+	LastTickAt         *time.Time `json:"last_tick_at,omitempty"`
+	LastTickDurationMs int64      `json:"last_tick_duration_ms"`
+	NextTickAt         *time.Time `json:"next_tick_at,omitempty"`
+	// SendingWindowOpen is false during quiet hours, when only exempt messages are sent
+	SendingWindowOpen bool `json:"sending_window_open"`
+	// CircuitBreaker is the state of the webhook circuit breaker, empty when none is configured
+	CircuitBreaker string             `json:"circuit_breaker,omitempty"`
+	LastTick       SchedulerTickStats `json:"last_tick"`
+	SinceStart     SchedulerTickStats `json:"since_start"`
+	Settings       SchedulerSettings  `json:"settings"`
+}
+
+// SchedulerSettings are the effective values of the tunables that can be changed at runtime
+type SchedulerSettings struct {
+	IntervalInSeconds int `json:"interval_in_seconds"`
+	BatchSize         int `json:"batch_size"`
+	Concurrency       int `json:"concurrency"`
+}
+
+type SchedulerStatusResponse struct {
+	Status    string          `json:"status"`
+	Timestamp int64           `json:"timestamp"`
+	Data      SchedulerStatus `json:"data"`
+}
+
+// DispatchSummary is the outcome of a tick triggered manually
+type DispatchSummary struct {
+	BatchSize int `json:"batch_size"`
+	SchedulerTickStats
+}
+
+type DispatchSummaryResponse struct {
+	Status    string          `json:"status"`
+	Timestamp int64           `json:"timestamp"`
+	Data      DispatchSummary `json:"data"`
+}
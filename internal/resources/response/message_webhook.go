@@ -3,4 +3,6 @@ package response
 type WebhookResponse struct {
 	Message   string `json:"message"`
 	MessageID string `json:"messageId"`
+	// Endpoint is the address the message was delivered to, filled in by the sender
+	Endpoint string `json:"-"`
 }
@@ -1,6 +1,7 @@
 package router
 
 import (
+	_ "go-template-microservice/internal/resources/request"
 	_ "go-template-microservice/internal/resources/response"
 	_ "go-template-microservice/pkg/utils"
 
@@ -8,9 +9,76 @@ import (
 )
 
 func (r *router) RegisterMessageRoutes(router fiber.Router) {
+	r.RegisterMessageCreateRoute(router)
+	r.RegisterMessageListRoute(router)
+	r.RegisterMessageCreateBatchRoute(router)
 	r.RegisterMessageStartSchedulerRoute(router)
 	r.RegisterMessageStopSchedulerRoute(router)
+	r.RegisterMessageSchedulerStatusRoute(router)
+	r.RegisterMessageUpdateSchedulerRoute(router)
+	r.RegisterMessageDispatchRoute(router)
 	r.RegisterMessageListSentMessagesRoute(router)
+	// the :id routes come last so they don't shadow the static paths above
+	r.RegisterMessageGetRoute(router)
+	r.RegisterMessageCancelRoute(router)
+	r.RegisterMessageUpdateRoute(router)
+}
+
+// RegisterMessageCreateRoute registers the route to enqueue a new message
+// @Summary Create Message
+// @Description Enqueues a new message with PENDING status to be delivered by the scheduler
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param request body request.CreateMessageRequest true "Message to enqueue"
+// @Success 201 {object} response.MessageResponse
+// @Failure 400 {object} utils.HTTPValidationErrorResponse
+// @Failure 422 {object} utils.HTTPErrorResponse
+// @Failure 500 {object} utils.HTTPErrorResponse
+// @Router /messages [post]
+func (r *router) RegisterMessageCreateRoute(router fiber.Router) {
+	router.Post("/", r.messageHandler.CreateMessage)
+}
+
+// RegisterMessageListRoute registers the route to list messages
+// @Summary List Messages
+// @Description Lists messages newest first with filters and keyset cursor pagination. Pass the next_cursor of a page as cursor to fetch the following one
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param status query []string false "Statuses to include, repeat the parameter for several" collectionFormat(multi) Enums(PENDING, SENDING, SENT, FAILED, EXPIRED, CANCELLED)
+// @Param to query string false "Recipient"
+// @Param content query string false "Substring of the content"
+// @Param created_from query string false "Created at or after, RFC 3339"
+// @Param created_to query string false "Created before, RFC 3339"
+// @Param sent_from query string false "Sent at or after, RFC 3339"
+// @Param sent_to query string false "Sent before, RFC 3339"
+// @Param sort query string false "Field to sort and paginate by" Enums(created_at, sent_at) default(created_at)
+// @Param cursor query string false "Cursor of the page to fetch"
+// @Param limit query int false "Maximum number of messages to retrieve" default(10)
+// @Success 200 {object} response.MessagesPaginationResponse
+// @Failure 400 {object} utils.HTTPValidationErrorResponse
+// @Failure 422 {object} utils.HTTPErrorResponse
+// @Failure 500 {object} utils.HTTPErrorResponse
+// @Router /messages [get]
+func (r *router) RegisterMessageListRoute(router fiber.Router) {
+	router.Get("/", r.messageHandler.ListMessages)
+}
+
+// RegisterMessageCreateBatchRoute registers the route to enqueue messages in bulk
+// @Summary Create Messages In Bulk
+// @Description Enqueues up to 1000 messages in a single transaction. Invalid items are rejected individually and reported in the per-item results
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param request body request.CreateMessagesBatchRequest true "Messages to enqueue"
+// @Success 200 {object} response.BatchMessagesResponse
+// @Failure 400 {object} utils.HTTPValidationErrorResponse
+// @Failure 422 {object} utils.HTTPErrorResponse
+// @Failure 500 {object} utils.HTTPErrorResponse
+// @Router /messages/batch [post]
+func (r *router) RegisterMessageCreateBatchRoute(router fiber.Router) {
+	router.Post("/batch", r.messageHandler.CreateMessagesBatch)
 }
 
 // RegisterMessageListSentMessagesRoute registers the route to list sent messages
@@ -52,3 +120,97 @@ func (r *router) RegisterMessageStartSchedulerRoute(router fiber.Router) {
 func (r *router) RegisterMessageStopSchedulerRoute(router fiber.Router) {
 	router.Post("/stop", r.messageHandler.StopScheduler)
 }
+
+// RegisterMessageSchedulerStatusRoute registers the route to inspect the message scheduler
+// @Summary Get Message Scheduler Status
+// @Description Returns whether the scheduler is running, its tick timings and the number of messages sent, retried and failed in the last tick and since start
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Success 200 {object} response.SchedulerStatusResponse
+// @Router /messages/scheduler [get]
+func (r *router) RegisterMessageSchedulerStatusRoute(router fiber.Router) {
+	router.Get("/scheduler", r.messageHandler.GetSchedulerStatus)
+}
+
+// RegisterMessageUpdateSchedulerRoute registers the route to change the scheduler settings at runtime
+// @Summary Update Message Scheduler
+// @Description Changes the interval, batch size and concurrency of the scheduler without a restart. Omitted fields keep their value, a tick in progress finishes with the previous settings
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param request body request.UpdateSchedulerRequest true "Settings to change"
+// @Success 200 {object} response.SchedulerStatusResponse
+// @Failure 400 {object} utils.HTTPValidationErrorResponse
+// @Failure 422 {object} utils.HTTPErrorResponse
+// @Router /messages/scheduler [patch]
+func (r *router) RegisterMessageUpdateSchedulerRoute(router fiber.Router) {
+	router.Patch("/scheduler", r.messageHandler.UpdateScheduler)
+}
+
+// RegisterMessageDispatchRoute registers the route to run a single scheduler tick on demand
+// @Summary Dispatch Messages
+// @Description Runs one scheduler tick synchronously, whether or not the scheduler is running, and returns how many messages were sent, retried, failed and skipped. A tick of the running scheduler in progress is waited for first
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param request body request.DispatchMessagesRequest false "Optional batch size override"
+// @Success 200 {object} response.DispatchSummaryResponse
+// @Failure 400 {object} utils.HTTPValidationErrorResponse
+// @Failure 422 {object} utils.HTTPErrorResponse
+// @Router /messages/dispatch [post]
+func (r *router) RegisterMessageDispatchRoute(router fiber.Router) {
+	router.Post("/dispatch", r.messageHandler.DispatchMessages)
+}
+
+// RegisterMessageGetRoute registers the route to retrieve a single message
+// @Summary Get Message
+// @Description Retrieves a single message by its ID
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path int true "Message ID"
+// @Success 200 {object} response.MessageResponse
+// @Failure 404 {object} utils.HTTPErrorResponse
+// @Failure 500 {object} utils.HTTPErrorResponse
+// @Router /messages/{id} [get]
+func (r *router) RegisterMessageGetRoute(router fiber.Router) {
+	router.Get("/:id<int>", r.messageHandler.GetMessage)
+}
+
+// RegisterMessageCancelRoute registers the route to cancel a pending message
+// @Summary Cancel Message
+// @Description Moves a PENDING message to CANCELLED so it is never sent. Messages being sent or already in a final status are refused. When updated_at is given the cancellation is refused if the message was modified since
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path int true "Message ID"
+// @Param updated_at query string false "The updated_at last read, RFC 3339"
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} utils.HTTPValidationErrorResponse
+// @Failure 404 {object} utils.HTTPErrorResponse
+// @Failure 409 {object} utils.HTTPErrorResponse
+// @Failure 500 {object} utils.HTTPErrorResponse
+// @Router /messages/{id} [delete]
+func (r *router) RegisterMessageCancelRoute(router fiber.Router) {
+	router.Delete("/:id<int>", r.messageHandler.CancelMessage)
+}
+
+// RegisterMessageUpdateRoute registers the route to edit a pending message
+// @Summary Update Message
+// @Description Edits the recipient or content of a PENDING message. When updated_at is given the update is refused if the message was modified since
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path int true "Message ID"
+// @Param request body request.UpdateMessageRequest true "Fields to change"
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} utils.HTTPValidationErrorResponse
+// @Failure 404 {object} utils.HTTPErrorResponse
+// @Failure 409 {object} utils.HTTPErrorResponse
+// @Failure 422 {object} utils.HTTPErrorResponse
+// @Failure 500 {object} utils.HTTPErrorResponse
+// @Router /messages/{id} [patch]
+func (r *router) RegisterMessageUpdateRoute(router fiber.Router) {
+	router.Patch("/:id<int>", r.messageHandler.UpdateMessage)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"go-template-microservice/docs"
 	"go-template-microservice/internal/handlers"
+	"go-template-microservice/internal/services"
 	"go-template-microservice/pkg/utils"
 
 	"github.com/gofiber/fiber/v2"
@@ -16,6 +17,7 @@ type IRouter interface {
 
 type router struct {
 	messageHandler handlers.MessageHandler
+	circuitBreaker *services.CircuitBreaker
 	logger         *logrus.Logger
 }
 
@@ -27,15 +29,21 @@ type router struct {
 // @license.url   http://www.apache.org/licenses/LICENSE-2.0.html
 // @BasePath /
 // swag init --parseDependency -g internal/router/router.go -o docs
-func NewRouter(messageHandler handlers.MessageHandler, logger *logrus.Logger) IRouter {
+func NewRouter(messageHandler handlers.MessageHandler, circuitBreaker *services.CircuitBreaker, logger *logrus.Logger) IRouter {
 	return &router{
 		messageHandler: messageHandler,
+		circuitBreaker: circuitBreaker,
 		logger:         logger,
 	}
 }
 func (r *router) RegisterRoutes(app *fiber.App) {
 	app.Get("/health", func(ctx *fiber.Ctx) error {
-		return ctx.Status(fiber.StatusOK).JSON(utils.NewSuccessResponse(fiber.Map{"service": "up"}))
+		health := fiber.Map{"service": "up"}
+		// an open circuit means the webhook is failing, the service itself is still up
+		if r.circuitBreaker != nil {
+			health["circuit_breaker"] = r.circuitBreaker.State()
+		}
+		return ctx.Status(fiber.StatusOK).JSON(utils.NewSuccessResponse(health))
 	})
 
 	messageRouter := app.Group("/messages")
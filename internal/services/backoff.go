@@ -0,0 +1,47 @@
+package services
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// ExponentialBackoff computes the delay before the next delivery attempt.
+// The delay doubles with every attempt starting from Base and is capped at Max,
+// half of it is randomized (equal jitter) so failed messages don't retry in lockstep.
+type ExponentialBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+func NewExponentialBackoff(base, max time.Duration) ExponentialBackoff {
+	return ExponentialBackoff{Base: base, Max: max}
+}
+
+// Delay returns the wait time after the given attempt, attempt numbering starts at 1
+func (b ExponentialBackoff) Delay(attempt int) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := b.Base
+	for i := 1; i < attempt; i++ {
+		if delay > math.MaxInt64/2 {
+			break
+		}
+		delay *= 2
+		if b.Max > 0 && delay >= b.Max {
+			delay = b.Max
+			break
+		}
+	}
+	if b.Max > 0 && delay > b.Max {
+		delay = b.Max
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(delay-half)+1))
+}
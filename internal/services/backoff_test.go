@@ -0,0 +1,44 @@
+package services_test
+
+import (
+	"time"
+
+	"go-template-microservice/internal/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ExponentialBackoff", func() {
+	Describe("Delay", func() {
+		backoff := services.NewExponentialBackoff(10*time.Second, 5*time.Minute)
+
+		It("should stay within the jitter range of the base delay on the first attempt", func() {
+			for i := 0; i < 50; i++ {
+				delay := backoff.Delay(1)
+				Expect(delay).To(BeNumerically(">=", 5*time.Second))
+				Expect(delay).To(BeNumerically("<=", 10*time.Second))
+			}
+		})
+
+		It("should double the delay with every attempt", func() {
+			for i := 0; i < 50; i++ {
+				delay := backoff.Delay(3)
+				Expect(delay).To(BeNumerically(">=", 20*time.Second))
+				Expect(delay).To(BeNumerically("<=", 40*time.Second))
+			}
+		})
+
+		It("should cap the delay at the configured maximum", func() {
+			for i := 0; i < 50; i++ {
+				delay := backoff.Delay(100)
+				Expect(delay).To(BeNumerically(">=", 150*time.Second))
+				Expect(delay).To(BeNumerically("<=", 5*time.Minute))
+			}
+		})
+
+		It("should return zero when no base delay is configured", func() {
+			Expect(services.NewExponentialBackoff(0, time.Minute).Delay(3)).To(BeZero())
+		})
+	})
+})
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-template-microservice/internal/resources/response"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCircuitOpen is returned instead of sending while the circuit breaker is open, the message
+// never reached the gateway and should go back to the queue without counting as an attempt
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type CircuitState string
+
+const (
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen rejects every send until the cool-down has passed
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a single trial send through, its outcome closes or reopens the circuit
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failed sends that open the circuit
+	FailureThreshold int
+	// CoolDown is how long the circuit stays open before a trial send is allowed
+	CoolDown time.Duration
+}
+
+// CircuitBreaker stops sends to a gateway that keeps failing. Every error but rejections and rate
+// limits counts as a failure, those prove the gateway is up. A nil breaker is always closed.
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	opts     CircuitBreakerOptions
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	// trial is set while the half-open trial send is in flight
+	trial  bool
+	logger *logrus.Logger
+}
+
+func NewCircuitBreaker(opts CircuitBreakerOptions, logger *logrus.Logger) *CircuitBreaker {
+	if opts.FailureThreshold < 1 {
+		opts.FailureThreshold = 1
+	}
+
+	return &CircuitBreaker{
+		opts:   opts,
+		state:  CircuitClosed,
+		logger: logger,
+	}
+}
+
+// State returns the current state, an open circuit whose cool-down has passed is reported as half-open
+func (b *CircuitBreaker) State() CircuitState {
+	if b == nil {
+		return CircuitClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.opts.CoolDown {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// allow reports whether a send may go through and moves an open circuit to half-open once the
+// cool-down has passed
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.opts.CoolDown {
+			return false
+		}
+		b.state = CircuitHalfOpen
+		b.trial = true
+		b.logger.Info("Circuit breaker half-open, sending a trial message")
+		return true
+	case CircuitHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record updates the circuit with the outcome of a send that was allowed through
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasTrial := b.state == CircuitHalfOpen
+	b.trial = false
+
+	if err == nil || gatewayAnswered(err) {
+		if wasTrial {
+			b.logger.Info("Circuit breaker closed")
+		}
+		b.state = CircuitClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if wasTrial || b.failures >= b.opts.FailureThreshold {
+		if b.state != CircuitOpen {
+			b.logger.WithField("failures", b.failures).Warn("Circuit breaker opened")
+		}
+		b.state = CircuitOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// release gives back a trial that ended without an outcome, e.g. a cancelled send
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// circuitBreakerSender guards another sender with a circuit breaker
+type circuitBreakerSender struct {
+	sender  MessageSenderService
+	breaker *CircuitBreaker
+	logger  *logrus.Logger
+}
+
+// NewCircuitBreakerSender returns ErrCircuitOpen without calling sender while breaker is open
+func NewCircuitBreakerSender(sender MessageSenderService, breaker *CircuitBreaker, logger *logrus.Logger) MessageSenderService {
+	return &circuitBreakerSender{
+		sender:  sender,
+		breaker: breaker,
+		logger:  logger,
+	}
+}
+
+func (s *circuitBreakerSender) Send(ctx context.Context, to, content string) (*response.WebhookResponse, error) {
+	if !s.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := s.sender.Send(ctx, to, content)
+	if err != nil && ctx.Err() != nil {
+		s.breaker.release()
+		return nil, err
+	}
+	s.breaker.record(err)
+	return resp, err
+}
@@ -0,0 +1,96 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go-template-microservice/internal/resources/response"
+	"go-template-microservice/internal/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("CircuitBreaker", func() {
+	var (
+		breaker      *services.CircuitBreaker
+		sender       services.MessageSenderService
+		transientErr = &services.TransientError{StatusCode: http.StatusInternalServerError, Err: errors.New("internal error")}
+	)
+
+	BeforeEach(func() {
+		breaker = services.NewCircuitBreaker(services.CircuitBreakerOptions{FailureThreshold: 2, CoolDown: 50 * time.Millisecond}, logger)
+		sender = services.NewCircuitBreakerSender(messageSenderMock, breaker, logger)
+	})
+
+	send := func() error {
+		_, err := sender.Send(context.Background(), "+905551234567", "Hello")
+		return err
+	}
+
+	It("should report a nil breaker as closed", func() {
+		var nilBreaker *services.CircuitBreaker
+		Expect(nilBreaker.State()).To(Equal(services.CircuitClosed))
+	})
+
+	It("should open after consecutive transient failures and refuse sends without calling the gateway", func() {
+		messageSenderMock.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, transientErr).Times(2)
+
+		Expect(send()).To(MatchError(transientErr))
+		Expect(breaker.State()).To(Equal(services.CircuitClosed))
+		Expect(send()).To(MatchError(transientErr))
+		Expect(breaker.State()).To(Equal(services.CircuitOpen))
+
+		Expect(send()).To(MatchError(services.ErrCircuitOpen))
+	})
+
+	It("should count unclassified errors, e.g. a failed auth, as failures", func() {
+		messageSenderMock.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("failed to sign request")).Times(2)
+
+		Expect(send()).To(HaveOccurred())
+		Expect(send()).To(HaveOccurred())
+		Expect(breaker.State()).To(Equal(services.CircuitOpen))
+	})
+
+	It("should not count rejections and rate limits as failures", func() {
+		gomock.InOrder(
+			messageSenderMock.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, transientErr),
+			messageSenderMock.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(nil, &services.PermanentError{StatusCode: http.StatusBadRequest, Err: errors.New("bad request")}),
+			messageSenderMock.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, transientErr),
+			messageSenderMock.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(nil, &services.RateLimitedError{Err: errors.New("too many requests")}),
+		)
+
+		for i := 0; i < 4; i++ {
+			Expect(send()).NotTo(MatchError(services.ErrCircuitOpen))
+		}
+		Expect(breaker.State()).To(Equal(services.CircuitClosed))
+	})
+
+	It("should close again when the trial send after the cool-down succeeds", func() {
+		messageSenderMock.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, transientErr).Times(2)
+		Expect(send()).To(HaveOccurred())
+		Expect(send()).To(HaveOccurred())
+		Expect(breaker.State()).To(Equal(services.CircuitOpen))
+
+		Eventually(breaker.State).Should(Equal(services.CircuitHalfOpen))
+		messageSenderMock.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Return(&response.WebhookResponse{MessageID: "ext"}, nil).Times(1)
+		Expect(send()).To(Succeed())
+		Expect(breaker.State()).To(Equal(services.CircuitClosed))
+	})
+
+	It("should reopen when the trial send fails", func() {
+		messageSenderMock.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, transientErr).Times(3)
+		Expect(send()).To(HaveOccurred())
+		Expect(send()).To(HaveOccurred())
+
+		Eventually(breaker.State).Should(Equal(services.CircuitHalfOpen))
+		Expect(send()).To(MatchError(transientErr))
+		Expect(breaker.State()).To(Equal(services.CircuitOpen))
+		Expect(send()).To(MatchError(services.ErrCircuitOpen))
+	})
+})
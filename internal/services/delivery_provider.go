@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go-template-microservice/internal/models"
+	"go-template-microservice/internal/resources/response"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ProviderRegistry holds the delivery providers by name. Every provider is a MessageSenderService
+// so a new gateway only needs a sender implementation and a Register call at startup.
+type ProviderRegistry struct {
+	providers map[string]MessageSenderService
+}
+
+func NewProviderRegistry() *ProviderRegistry {
+	return &ProviderRegistry{providers: make(map[string]MessageSenderService)}
+}
+
+// Register adds a provider, registering the same name again replaces the previous provider
+func (r *ProviderRegistry) Register(name string, sender MessageSenderService) {
+	r.providers[strings.ToLower(name)] = sender
+}
+
+// Get returns the provider registered under name
+func (r *ProviderRegistry) Get(name string) (MessageSenderService, bool) {
+	sender, ok := r.providers[strings.ToLower(name)]
+	return sender, ok
+}
+
+// Names returns the registered provider names in alphabetical order
+func (r *ProviderRegistry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DeliveryRoute carries the attributes of the message being sent that routing rules may match
+// on besides the recipient, which every sender already receives
+type DeliveryRoute struct {
+	Category string
+	Priority models.Priority
+}
+
+type deliveryRouteKey struct{}
+
+// WithDeliveryRoute attaches the route of the message to the context of its send
+func WithDeliveryRoute(ctx context.Context, route DeliveryRoute) context.Context {
+	return context.WithValue(ctx, deliveryRouteKey{}, route)
+}
+
+func deliveryRouteFrom(ctx context.Context) DeliveryRoute {
+	route, _ := ctx.Value(deliveryRouteKey{}).(DeliveryRoute)
+	return route
+}
+
+// RoutingRule sends the messages it matches through Provider. A rule matches either a recipient
+// prefix ("to:+90"), a category ("category:otp") or a priority ("priority:high").
+type RoutingRule struct {
+	Field    string
+	Value    string
+	Provider string
+}
+
+func (r RoutingRule) matches(to string, route DeliveryRoute) bool {
+	switch r.Field {
+	case "to":
+		return strings.HasPrefix(to, r.Value)
+	case "category":
+		return route.Category == r.Value
+	case "priority":
+		return string(route.Priority) == r.Value
+	}
+	return false
+}
+
+// ParseRoutingRules parses rules written as "<field>:<value>=<provider>", e.g. "category:otp=smtp"
+func ParseRoutingRules(specs []string) ([]RoutingRule, error) {
+	var rules []RoutingRule
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		match, provider, ok := strings.Cut(spec, "=")
+		if !ok || strings.TrimSpace(provider) == "" {
+			return nil, fmt.Errorf("invalid routing rule %q, expected <field>:<value>=<provider>", spec)
+		}
+		field, value, ok := strings.Cut(match, ":")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid routing rule %q, expected <field>:<value>=<provider>", spec)
+		}
+
+		rule := RoutingRule{
+			Field:    strings.ToLower(strings.TrimSpace(field)),
+			Value:    strings.TrimSpace(value),
+			Provider: strings.ToLower(strings.TrimSpace(provider)),
+		}
+		switch rule.Field {
+		case "to":
+		case "category", "priority":
+			rule.Value = strings.ToLower(rule.Value)
+		default:
+			return nil, fmt.Errorf("invalid routing rule %q, unknown field %q", spec, rule.Field)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// routingSender picks the provider of every message from the first matching rule and falls
+// back to the default provider when no rule matches
+type routingSender struct {
+	registry        *ProviderRegistry
+	rules           []RoutingRule
+	defaultProvider string
+	logger          *logrus.Logger
+}
+
+// NewRoutingSender fails when the default provider or a provider referenced by a rule is not registered
+func NewRoutingSender(registry *ProviderRegistry, defaultProvider string, rules []RoutingRule, logger *logrus.Logger) (MessageSenderService, error) {
+	defaultProvider = strings.ToLower(defaultProvider)
+	if _, ok := registry.Get(defaultProvider); !ok {
+		return nil, fmt.Errorf("unknown delivery provider %q, registered providers: %s", defaultProvider, strings.Join(registry.Names(), ", "))
+	}
+	for _, rule := range rules {
+		if _, ok := registry.Get(rule.Provider); !ok {
+			return nil, fmt.Errorf("routing rule %s:%s uses unknown delivery provider %q", rule.Field, rule.Value, rule.Provider)
+		}
+	}
+
+	return &routingSender{
+		registry:        registry,
+		rules:           rules,
+		defaultProvider: defaultProvider,
+		logger:          logger,
+	}, nil
+}
+
+func (s *routingSender) Send(ctx context.Context, to, content string) (*response.WebhookResponse, error) {
+	name := s.route(to, deliveryRouteFrom(ctx))
+	sender, _ := s.registry.Get(name)
+	s.logger.WithField("provider", name).Debug("Routing message to delivery provider")
+	return sender.Send(ctx, to, content)
+}
+
+func (s *routingSender) route(to string, route DeliveryRoute) string {
+	for _, rule := range s.rules {
+		if rule.matches(to, route) {
+			return rule.Provider
+		}
+	}
+	return s.defaultProvider
+}
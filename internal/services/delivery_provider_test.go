@@ -0,0 +1,115 @@
+package services_test
+
+import (
+	"context"
+
+	"go-template-microservice/internal/models"
+	"go-template-microservice/internal/resources/response"
+	"go-template-microservice/internal/services"
+	serviceMocks "go-template-microservice/internal/services/mocks"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Delivery Providers", func() {
+	Describe("ParseRoutingRules", func() {
+		It("should parse recipient, category and priority rules", func() {
+			rules, err := services.ParseRoutingRules([]string{"to:+1=Form", " category:OTP=smtp ", "", "priority:high=json"})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rules).To(Equal([]services.RoutingRule{
+				{Field: "to", Value: "+1", Provider: "form"},
+				{Field: "category", Value: "otp", Provider: "smtp"},
+				{Field: "priority", Value: "high", Provider: "json"},
+			}))
+		})
+
+		DescribeTable("should reject malformed rules",
+			func(spec string) {
+				_, err := services.ParseRoutingRules([]string{spec})
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("missing provider", "to:+1"),
+			Entry("empty provider", "to:+1="),
+			Entry("missing value", "category=smtp"),
+			Entry("unknown field", "country:tr=smtp"),
+		)
+	})
+
+	Describe("RoutingSender", func() {
+		var (
+			registry  *services.ProviderRegistry
+			formMock  *serviceMocks.MockMessageSenderService
+			smtpMock  *serviceMocks.MockMessageSenderService
+			otpRoute  context.Context
+			plainCtx  context.Context
+			rules     []services.RoutingRule
+			routeErr  error
+			formReply = &response.WebhookResponse{MessageID: "ext-form"}
+		)
+
+		BeforeEach(func() {
+			formMock = serviceMocks.NewMockMessageSenderService(mockCtrl)
+			smtpMock = serviceMocks.NewMockMessageSenderService(mockCtrl)
+			registry = services.NewProviderRegistry()
+			registry.Register("json", messageSenderMock)
+			registry.Register("form", formMock)
+			registry.Register("smtp", smtpMock)
+
+			rules, routeErr = services.ParseRoutingRules([]string{"category:otp=smtp", "to:+1=form"})
+			Expect(routeErr).NotTo(HaveOccurred())
+
+			plainCtx = context.Background()
+			otpRoute = services.WithDeliveryRoute(plainCtx, services.DeliveryRoute{Category: "otp", Priority: models.PriorityHigh})
+		})
+
+		It("should send through the default provider when no rule matches", func() {
+			messageSenderMock.EXPECT().Send(gomock.Any(), "+905551234567", "Hello").Return(&response.WebhookResponse{MessageID: "ext-json"}, nil)
+
+			sender, err := services.NewRoutingSender(registry, "json", rules, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			resp, err := sender.Send(plainCtx, "+905551234567", "Hello")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.MessageID).To(Equal("ext-json"))
+		})
+
+		It("should route by recipient prefix", func() {
+			formMock.EXPECT().Send(gomock.Any(), "+15551234567", "Hello").Return(formReply, nil)
+
+			sender, err := services.NewRoutingSender(registry, "json", rules, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			resp, err := sender.Send(plainCtx, "+15551234567", "Hello")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp).To(Equal(formReply))
+		})
+
+		It("should apply the first matching rule", func() {
+			smtpMock.EXPECT().Send(gomock.Any(), "+15551234567", "Your code is 1234").Return(&response.WebhookResponse{MessageID: "ext-smtp"}, nil)
+
+			sender, err := services.NewRoutingSender(registry, "json", rules, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			resp, err := sender.Send(otpRoute, "+15551234567", "Your code is 1234")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.MessageID).To(Equal("ext-smtp"))
+		})
+
+		It("should reject an unknown default provider", func() {
+			_, err := services.NewRoutingSender(registry, "smpp", nil, logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("form, json, smtp"))
+		})
+
+		It("should reject rules referencing unknown providers", func() {
+			rules, err := services.ParseRoutingRules([]string{"to:+44=smpp"})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = services.NewRoutingSender(registry, "json", rules, logger)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
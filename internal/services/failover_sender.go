@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-template-microservice/internal/resources/response"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WebhookEndpoint is one of the webhook URLs messages are spread over in proportion to Weight
+type WebhookEndpoint struct {
+	URL    string
+	Weight int
+}
+
+// ParseWebhookEndpoints parses endpoints written as "<url>" or "<url>|<weight>", the weight defaults to 1
+func ParseWebhookEndpoints(specs []string) ([]WebhookEndpoint, error) {
+	var endpoints []WebhookEndpoint
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		endpoint := WebhookEndpoint{URL: spec, Weight: 1}
+		if url, weight, ok := strings.Cut(spec, "|"); ok {
+			w, err := strconv.Atoi(strings.TrimSpace(weight))
+			if err != nil || w < 1 {
+				return nil, fmt.Errorf("invalid weight %q of webhook endpoint %q, expected a positive integer", weight, url)
+			}
+			endpoint = WebhookEndpoint{URL: strings.TrimSpace(url), Weight: w}
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+type FailoverOptions struct {
+	// FailureThreshold is the number of consecutive failed sends that mark an endpoint unhealthy
+	FailureThreshold int
+	// ProbeInterval is how often unhealthy endpoints are probed to bring them back
+	ProbeInterval time.Duration
+}
+
+// endpointState tracks the passive health of an endpoint, guarded by failoverSender.mu
+type endpointState struct {
+	WebhookEndpoint
+	sender   MessageSenderService
+	healthy  bool
+	failures int
+}
+
+// failoverSender spreads sends over several webhook endpoints by weight. Any error but a rejection
+// or a rate limit moves the send to the next endpoint and an endpoint failing FailureThreshold
+// times in a row stops receiving traffic until a probe gets a response from it again.
+type failoverSender struct {
+	mu        sync.Mutex
+	endpoints []*endpointState
+	opts      FailoverOptions
+	client    *http.Client
+	logger    *logrus.Logger
+}
+
+// NewFailoverSender builds a sender per endpoint with newSender and probes unhealthy endpoints until ctx is done
+func NewFailoverSender(
+	ctx context.Context,
+	endpoints []WebhookEndpoint,
+	newSender func(url string) MessageSenderService,
+	opts FailoverOptions,
+	logger *logrus.Logger,
+) MessageSenderService {
+	if opts.FailureThreshold < 1 {
+		opts.FailureThreshold = 1
+	}
+	if opts.ProbeInterval <= 0 {
+		opts.ProbeInterval = 30 * time.Second
+	}
+
+	s := &failoverSender{
+		opts:   opts,
+		client: &http.Client{Timeout: 5 * time.Second},
+		logger: logger,
+	}
+	for _, endpoint := range endpoints {
+		s.endpoints = append(s.endpoints, &endpointState{
+			WebhookEndpoint: endpoint,
+			sender:          newSender(endpoint.URL),
+			healthy:         true,
+		})
+	}
+
+	go s.probeLoop(ctx)
+	return s
+}
+
+func (s *failoverSender) Send(ctx context.Context, to, content string) (*response.WebhookResponse, error) {
+	candidates := s.healthyInWeightedOrder()
+	if len(candidates) == 0 {
+		return nil, &TransientError{Err: errors.New("no healthy webhook endpoint")}
+	}
+
+	var lastErr error
+	for _, endpoint := range candidates {
+		resp, err := endpoint.sender.Send(ctx, to, content)
+		if err == nil {
+			s.recordSuccess(endpoint)
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		if gatewayAnswered(err) {
+			// the endpoint answered, the error belongs to the message
+			s.recordSuccess(endpoint)
+			return nil, err
+		}
+		s.recordFailure(endpoint)
+		s.logger.WithError(err).WithField("endpoint", endpoint.URL).Warn("Webhook endpoint failed, trying the next one")
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// healthyInWeightedOrder returns the healthy endpoints in a random order where heavier endpoints
+// tend to come first, the first one receives a share of the traffic proportional to its weight
+func (s *failoverSender) healthyInWeightedOrder() []*endpointState {
+	s.mu.Lock()
+	var pool []*endpointState
+	total := 0
+	for _, endpoint := range s.endpoints {
+		if endpoint.healthy {
+			pool = append(pool, endpoint)
+			total += endpoint.Weight
+		}
+	}
+	s.mu.Unlock()
+
+	ordered := make([]*endpointState, 0, len(pool))
+	for len(pool) > 0 {
+		pick := rand.IntN(total)
+		for i, endpoint := range pool {
+			if pick -= endpoint.Weight; pick < 0 {
+				ordered = append(ordered, endpoint)
+				total -= endpoint.Weight
+				pool = append(pool[:i], pool[i+1:]...)
+				break
+			}
+		}
+	}
+	return ordered
+}
+
+func (s *failoverSender) recordSuccess(endpoint *endpointState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoint.failures = 0
+}
+
+func (s *failoverSender) recordFailure(endpoint *endpointState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoint.failures++
+	if endpoint.healthy && endpoint.failures >= s.opts.FailureThreshold {
+		endpoint.healthy = false
+		s.logger.WithFields(logrus.Fields{
+			"endpoint": endpoint.URL,
+			"failures": endpoint.failures,
+		}).Warn("Webhook endpoint marked unhealthy")
+	}
+}
+
+func (s *failoverSender) probeLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.probeUnhealthy(ctx)
+		}
+	}
+}
+
+func (s *failoverSender) probeUnhealthy(ctx context.Context) {
+	s.mu.Lock()
+	var unhealthy []*endpointState
+	for _, endpoint := range s.endpoints {
+		if !endpoint.healthy {
+			unhealthy = append(unhealthy, endpoint)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, endpoint := range unhealthy {
+		if !s.probe(ctx, endpoint.URL) {
+			continue
+		}
+		s.mu.Lock()
+		endpoint.healthy = true
+		endpoint.failures = 0
+		s.mu.Unlock()
+		s.logger.WithField("endpoint", endpoint.URL).Info("Webhook endpoint is healthy again")
+	}
+}
+
+// probe sends a HEAD request, any answer below 500 means the endpoint is back even if it doesn't support HEAD
+func (s *failoverSender) probe(ctx context.Context, url string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < http.StatusInternalServerError
+}
@@ -0,0 +1,191 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"go-template-microservice/internal/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// switchableWebhookServer answers 202 while up and 500 while down, counting the sends it receives
+type switchableWebhookServer struct {
+	*httptest.Server
+	down  atomic.Bool
+	sends atomic.Int32
+}
+
+func newSwitchableWebhookServer() *switchableWebhookServer {
+	s := &switchableWebhookServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.down.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		s.sends.Add(1)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext"}`))
+	}))
+	return s
+}
+
+// failingAuth fails every request before it is sent, like credentials that can't be obtained
+type failingAuth struct{}
+
+func (failingAuth) Apply(context.Context, *http.Request) error {
+	return errors.New("credentials unavailable")
+}
+
+var _ = Describe("FailoverSender", func() {
+	newWebhookSender := func(url string) services.MessageSenderService {
+		return services.NewMessageSenderService(url, "test-auth-key", logger)
+	}
+
+	Describe("ParseWebhookEndpoints", func() {
+		It("should parse weights and default them to 1", func() {
+			endpoints, err := services.ParseWebhookEndpoints([]string{"http://a/webhook|3", " http://b/webhook ", ""})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(endpoints).To(Equal([]services.WebhookEndpoint{
+				{URL: "http://a/webhook", Weight: 3},
+				{URL: "http://b/webhook", Weight: 1},
+			}))
+		})
+
+		It("should reject weights that are not positive integers", func() {
+			_, err := services.ParseWebhookEndpoints([]string{"http://a/webhook|0"})
+			Expect(err).To(HaveOccurred())
+
+			_, err = services.ParseWebhookEndpoints([]string{"http://a/webhook|heavy"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Send", func() {
+		var (
+			primary, secondary *switchableWebhookServer
+			probeCtx           context.Context
+			cancelProbes       context.CancelFunc
+		)
+
+		BeforeEach(func() {
+			primary, secondary = newSwitchableWebhookServer(), newSwitchableWebhookServer()
+			probeCtx, cancelProbes = context.WithCancel(context.Background())
+		})
+
+		AfterEach(func() {
+			cancelProbes()
+			primary.Close()
+			secondary.Close()
+		})
+
+		It("should spread sends over the endpoints by weight", func() {
+			sender := services.NewFailoverSender(probeCtx, []services.WebhookEndpoint{
+				{URL: primary.URL, Weight: 9},
+				{URL: secondary.URL, Weight: 1},
+			}, newWebhookSender, services.FailoverOptions{FailureThreshold: 3}, logger)
+
+			for i := 0; i < 200; i++ {
+				_, err := sender.Send(context.Background(), "+905551234567", "Hello")
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(primary.sends.Load()).To(BeNumerically(">", 150))
+			Expect(secondary.sends.Load()).To(BeNumerically(">", 0))
+		})
+
+		It("should fail over to the next endpoint and record where the message went", func() {
+			primary.down.Store(true)
+			sender := services.NewFailoverSender(probeCtx, []services.WebhookEndpoint{
+				{URL: primary.URL, Weight: 1},
+				{URL: secondary.URL, Weight: 1},
+			}, newWebhookSender, services.FailoverOptions{FailureThreshold: 100}, logger)
+
+			for i := 0; i < 10; i++ {
+				resp, err := sender.Send(context.Background(), "+905551234567", "Hello")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.Endpoint).To(Equal(secondary.URL))
+			}
+		})
+
+		It("should stop using an endpoint after consecutive failures until a probe brings it back", func() {
+			primary.down.Store(true)
+			sender := services.NewFailoverSender(probeCtx, []services.WebhookEndpoint{
+				{URL: primary.URL, Weight: 1},
+				{URL: secondary.URL, Weight: 1},
+			}, newWebhookSender, services.FailoverOptions{FailureThreshold: 1, ProbeInterval: 20 * time.Millisecond}, logger)
+
+			// the first failure marks the primary unhealthy, every later send goes to the secondary directly
+			for i := 0; i < 20; i++ {
+				_, err := sender.Send(context.Background(), "+905551234567", "Hello")
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(secondary.sends.Load()).To(BeEquivalentTo(20))
+
+			primary.down.Store(false)
+			Eventually(func() int32 {
+				_, err := sender.Send(context.Background(), "+905551234567", "Hello")
+				Expect(err).NotTo(HaveOccurred())
+				return primary.sends.Load()
+			}).WithTimeout(2 * time.Second).WithPolling(10 * time.Millisecond).Should(BeNumerically(">", 0))
+		})
+
+		It("should return a transient error when no endpoint is healthy", func() {
+			primary.down.Store(true)
+			sender := services.NewFailoverSender(probeCtx, []services.WebhookEndpoint{
+				{URL: primary.URL, Weight: 1},
+			}, newWebhookSender, services.FailoverOptions{FailureThreshold: 1, ProbeInterval: time.Hour}, logger)
+
+			_, err := sender.Send(context.Background(), "+905551234567", "Hello")
+			var transient *services.TransientError
+			Expect(errors.As(err, &transient)).To(BeTrue())
+			Expect(transient.StatusCode).To(Equal(http.StatusInternalServerError))
+
+			_, err = sender.Send(context.Background(), "+905551234567", "Hello")
+			Expect(errors.As(err, &transient)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("no healthy webhook endpoint"))
+		})
+
+		It("should fail over when the request to an endpoint can't be authenticated", func() {
+			sender := services.NewFailoverSender(probeCtx, []services.WebhookEndpoint{
+				{URL: primary.URL, Weight: 1000000},
+				{URL: secondary.URL, Weight: 1},
+			}, func(url string) services.MessageSenderService {
+				if url == primary.URL {
+					return services.NewMessageSenderService(url, "", logger, services.WithAuth(failingAuth{}))
+				}
+				return newWebhookSender(url)
+			}, services.FailoverOptions{FailureThreshold: 100}, logger)
+
+			resp, err := sender.Send(context.Background(), "+905551234567", "Hello")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Endpoint).To(Equal(secondary.URL))
+			Expect(primary.sends.Load()).To(BeZero())
+		})
+
+		It("should not fail over a message the endpoint rejected", func() {
+			rejecting := createMockWebhookServer(http.StatusBadRequest, `{"error":"bad request"}`)
+			defer rejecting.Close()
+
+			sender := services.NewFailoverSender(probeCtx, []services.WebhookEndpoint{
+				{URL: rejecting.URL, Weight: 1000000},
+				{URL: secondary.URL, Weight: 1},
+			}, newWebhookSender, services.FailoverOptions{FailureThreshold: 1}, logger)
+
+			_, err := sender.Send(context.Background(), "+905551234567", "Hello")
+			var permanent *services.PermanentError
+			Expect(errors.As(err, &permanent)).To(BeTrue())
+			Expect(secondary.sends.Load()).To(BeZero())
+		})
+	})
+})
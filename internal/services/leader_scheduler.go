@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-template-microservice/internal/models"
+	"go-template-microservice/internal/repository"
+	"go-template-microservice/internal/resources/response"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LeaderElectionOptions holds the tunables of the scheduler leader election
+type LeaderElectionOptions struct {
+	// Identity names this replica in the election, it must be unique across replicas
+	Identity string
+	// LeaseDuration is how long the leader keeps the lease without renewing it, a crashed
+	// leader is replaced at the latest after this duration
+	LeaseDuration time.Duration
+}
+
+// minRenewInterval keeps very short leases from renewing in a busy loop
+const minRenewInterval = 10 * time.Millisecond
+
+// leaderElectedScheduler makes sure only one replica runs the scheduling loop at a time.
+// Every replica campaigns for a Redis lease while the shared desired state is running and only
+// the lease holder runs the wrapped scheduler, so the scheduler fails over to any replica, not
+// only to the ones it was started on. The state is checked on every round so a stop requested
+// on any replica stops the leader as well.
+type leaderElectedScheduler struct {
+	// ctx is the root context of the campaign and of the wrapped scheduler
+	ctx    context.Context
+	inner  MessageScheduler
+	leases repository.LeaderLeaseRepository
+	states repository.SchedulerStateRepository
+
+	identity      string
+	leaseDuration time.Duration
+	renewInterval time.Duration
+
+	mu sync.Mutex
+	// wanted is whether the scheduler was started on this replica, it decides eligibility when
+	// there is no shared desired state
+	wanted bool
+	// withdrawn keeps a replica stopped locally, e.g. on shutdown, out of the election until it
+	// is started again or the shared state is stopped
+	withdrawn bool
+	wake      chan struct{}
+
+	// roundMu serializes campaign rounds with Stop, leader and applied are guarded by it
+	roundMu sync.Mutex
+	leader  bool
+	// applied are the shared settings last handed to the wrapped scheduler
+	applied models.SchedulerSettings
+
+	logger *logrus.Logger
+}
+
+func NewLeaderElectedScheduler(
+	ctx context.Context,
+	inner MessageScheduler,
+	leases repository.LeaderLeaseRepository,
+	states repository.SchedulerStateRepository,
+	opts LeaderElectionOptions,
+	logger *logrus.Logger,
+) (MessageScheduler, error) {
+	if opts.LeaseDuration <= 0 {
+		return nil, errors.New("leader lease duration must be positive")
+	}
+
+	s := &leaderElectedScheduler{
+		ctx:           ctx,
+		inner:         inner,
+		leases:        leases,
+		states:        states,
+		identity:      opts.Identity,
+		leaseDuration: opts.LeaseDuration,
+		// renew well before the lease runs out so a slow round doesn't cost us the leadership
+		renewInterval: max(opts.LeaseDuration/3, minRenewInterval),
+		wake:          make(chan struct{}, 1),
+		logger:        logger,
+	}
+	go s.campaign()
+	return s, nil
+}
+
+// Start makes this replica a candidate, the wrapped scheduler runs once it holds the lease
+func (s *leaderElectedScheduler) Start(_ context.Context) {
+	s.mu.Lock()
+	s.wanted = true
+	s.withdrawn = false
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Stop withdraws this replica from the election, stopping the wrapped scheduler and handing
+// the lease over right away when it is the leader
+func (s *leaderElectedScheduler) Stop(ctx context.Context) {
+	s.mu.Lock()
+	s.wanted = false
+	s.withdrawn = true
+	s.mu.Unlock()
+
+	s.roundMu.Lock()
+	defer s.roundMu.Unlock()
+	s.stepDown(ctx)
+}
+
+func (s *leaderElectedScheduler) Status() response.SchedulerStatus {
+	status := s.inner.Status()
+	status.Instance = s.identity
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	leader, err := s.leases.GetLeader(ctx)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get scheduler leader")
+	}
+	status.Leader = leader
+	return status
+}
+
+// RunOnce runs a tick on this replica even when it isn't the leader, claims keep a message
+// from being sent twice and the leader's own ticks are not affected
+func (s *leaderElectedScheduler) RunOnce(ctx context.Context, batchSize int) response.DispatchSummary {
+	return s.inner.RunOnce(ctx, batchSize)
+}
+
+// Reconfigure applies to this replica right away, the leader picks up the settings saved in the
+// state store on its next round
+func (s *leaderElectedScheduler) Reconfigure(settings SchedulerSettings) {
+	s.inner.Reconfigure(settings)
+}
+
+func (s *leaderElectedScheduler) campaign() {
+	ticker := time.NewTicker(s.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		s.round()
+
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		case <-s.ctx.Done():
+			s.roundMu.Lock()
+			if s.leader {
+				s.releaseLease()
+				s.leader = false
+			}
+			s.roundMu.Unlock()
+			return
+		}
+	}
+}
+
+// round renews or acquires the lease and starts or stops the wrapped scheduler accordingly
+func (s *leaderElectedScheduler) round() {
+	s.roundMu.Lock()
+	defer s.roundMu.Unlock()
+
+	if !s.eligible() {
+		s.stepDown(s.ctx)
+		return
+	}
+
+	var (
+		held bool
+		err  error
+	)
+	if s.leader {
+		held, err = s.leases.Renew(s.ctx, s.identity, s.leaseDuration)
+	} else {
+		held, err = s.leases.Acquire(s.ctx, s.identity, s.leaseDuration)
+	}
+	if err != nil {
+		// without a renewed lease another replica may take over any moment, stop sending
+		s.logger.WithError(err).WithField("identity", s.identity).Error("Leader election round failed")
+		s.stepDown(s.ctx)
+		return
+	}
+
+	if held {
+		s.applySettings()
+	}
+	switch {
+	case held && !s.leader:
+		s.logger.WithField("identity", s.identity).Info("Elected as scheduler leader")
+		s.leader = true
+		s.inner.Start(s.ctx)
+	case !held && s.leader:
+		s.logger.WithField("identity", s.identity).Warn("Lost scheduler leadership")
+		s.leader = false
+		s.stopInner(s.ctx)
+	}
+}
+
+// eligible reports whether this replica should compete for the lease: the shared desired state
+// must be running and the replica must not have been stopped locally. Without a state store,
+// or before any state was saved, the replica competes when the scheduler was started here.
+func (s *leaderElectedScheduler) eligible() bool {
+	s.mu.Lock()
+	wanted, withdrawn := s.wanted, s.withdrawn
+	s.mu.Unlock()
+
+	if s.states == nil {
+		return wanted
+	}
+	state, err := s.states.GetDesiredState(s.ctx)
+	if err != nil {
+		// keep the current role, the state is checked again on the next round
+		return s.leader || wanted
+	}
+
+	switch state {
+	case models.SchedulerStateRunning:
+		return !withdrawn
+	case models.SchedulerStateStopped:
+		// the scheduler was stopped everywhere, the next start applies to this replica too
+		s.mu.Lock()
+		s.withdrawn = false
+		s.mu.Unlock()
+		return false
+	default:
+		return wanted
+	}
+}
+
+// applySettings hands the settings saved on any replica to the wrapped scheduler when they changed
+// since the last round, so they follow the leadership to whichever replica holds it
+func (s *leaderElectedScheduler) applySettings() {
+	if s.states == nil {
+		return
+	}
+	settings, err := s.states.GetSettings(s.ctx)
+	if err != nil {
+		// keep the current settings, they are read again on the next round
+		return
+	}
+	if reflect.DeepEqual(settings, s.applied) {
+		return
+	}
+
+	s.applied = settings
+	s.inner.Reconfigure(NewSchedulerSettings(settings))
+}
+
+// stepDown stops the wrapped scheduler and releases the lease if this replica is the leader
+func (s *leaderElectedScheduler) stepDown(ctx context.Context) {
+	if !s.leader {
+		return
+	}
+
+	s.logger.WithField("identity", s.identity).Info("Stepping down as scheduler leader")
+	s.stopInner(ctx)
+	s.releaseLease()
+	s.leader = false
+}
+
+// stopInner waits at most a lease duration for in-flight sends, by then another replica may lead
+func (s *leaderElectedScheduler) stopInner(ctx context.Context) {
+	stopCtx, cancel := context.WithTimeout(ctx, s.leaseDuration)
+	defer cancel()
+	s.inner.Stop(stopCtx)
+}
+
+func (s *leaderElectedScheduler) releaseLease() {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(s.ctx), time.Second)
+	defer cancel()
+	if err := s.leases.Release(ctx, s.identity); err != nil {
+		s.logger.WithError(err).WithField("identity", s.identity).Warn("Failed to release leader lease, it expires on its own")
+	}
+}
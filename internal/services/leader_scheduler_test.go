@@ -0,0 +1,178 @@
+package services_test
+
+import (
+	"context"
+	"time"
+
+	"go-template-microservice/internal/models"
+	"go-template-microservice/internal/repository"
+	"go-template-microservice/internal/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("LeaderElectedScheduler", func() {
+	var (
+		// electionCtx ends the campaigns of the test's candidates, they campaign from construction
+		electionCtx    context.Context
+		cancelElection context.CancelFunc
+		leases         repository.LeaderLeaseRepository
+		states         repository.SchedulerStateRepository
+		inners         map[string]services.MessageScheduler
+		candidates     map[string]services.MessageScheduler
+	)
+
+	BeforeEach(func() {
+		err := redisInst.Client().Del(ctx, "scheduler:leader", "scheduler:desired_state", "scheduler:settings").Err()
+		Expect(err).NotTo(HaveOccurred())
+
+		messageRepoMock.EXPECT().ReleaseExpiredLeases().Return(int64(0), nil).AnyTimes()
+		messageRepoMock.EXPECT().ExpireMessages().Return(int64(0), nil).AnyTimes()
+		messageRepoMock.EXPECT().ClaimMessages(gomock.Any()).Return(nil, nil).AnyTimes()
+
+		electionCtx, cancelElection = context.WithCancel(ctx)
+		leases = repository.NewLeaderLeaseRepository(redisInst, logger)
+		states = repository.NewSchedulerStateRepository(redisInst, logger)
+		inners = map[string]services.MessageScheduler{}
+		candidates = map[string]services.MessageScheduler{}
+		for _, identity := range []string{"replica-a", "replica-b"} {
+			inners[identity] = services.NewMessageScheduler(
+				ctx,
+				messageRepoMock,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{Interval: 1 * time.Hour, BatchSize: 10, MaxAttempts: 3, WorkerID: identity},
+				logger,
+			)
+			candidate, err := services.NewLeaderElectedScheduler(
+				electionCtx,
+				inners[identity],
+				leases,
+				states,
+				services.LeaderElectionOptions{Identity: identity, LeaseDuration: 300 * time.Millisecond},
+				logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			candidates[identity] = candidate
+		}
+	})
+
+	AfterEach(func() {
+		for _, candidate := range candidates {
+			candidate.Stop(ctx)
+		}
+		cancelElection()
+	})
+
+	running := func() []string {
+		var identities []string
+		for identity, inner := range inners {
+			if inner.Status().Running {
+				identities = append(identities, identity)
+			}
+		}
+		return identities
+	}
+
+	electLeader := func() (string, string) {
+		for _, candidate := range candidates {
+			candidate.Start(ctx)
+		}
+		Eventually(running).Should(HaveLen(1))
+
+		leader := running()[0]
+		follower := "replica-a"
+		if leader == follower {
+			follower = "replica-b"
+		}
+		return leader, follower
+	}
+
+	It("should reject a lease duration that isn't positive", func() {
+		for _, leaseDuration := range []time.Duration{0, -time.Second} {
+			_, err := services.NewLeaderElectedScheduler(electionCtx, inners["replica-a"], leases, states, services.LeaderElectionOptions{Identity: "replica-c", LeaseDuration: leaseDuration}, logger)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+
+	It("should run the scheduler on exactly one replica", func() {
+		leader, follower := electLeader()
+
+		Consistently(running, 500*time.Millisecond).Should(Equal([]string{leader}))
+
+		status := candidates[follower].Status()
+		Expect(status.Running).To(BeFalse())
+		Expect(status.Instance).To(Equal(follower))
+		Expect(status.Leader).To(Equal(leader))
+		Expect(candidates[leader].Status().Running).To(BeTrue())
+	})
+
+	It("should hand the lease over when the leader is stopped", func() {
+		leader, follower := electLeader()
+
+		candidates[leader].Stop(ctx)
+
+		Eventually(running).Should(Equal([]string{follower}))
+		Expect(candidates[leader].Status().Leader).To(Equal(follower))
+	})
+
+	It("should fail over when the leader loses its lease", func() {
+		leader, follower := electLeader()
+
+		// the lease expiring while the leader is unreachable looks the same to the follower
+		err := redisInst.Client().Del(ctx, "scheduler:leader").Err()
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(running).Should(Equal([]string{follower}))
+		Consistently(running, 500*time.Millisecond).Should(Equal([]string{follower}))
+		Expect(candidates[leader].Status().Leader).To(Equal(follower))
+	})
+
+	It("should fail over to a replica the scheduler was never started on", func() {
+		// both replicas came up while the scheduler was stopped, it was then started through
+		// replica-a which only saved the desired state so far
+		Expect(states.SetDesiredState(ctx, models.SchedulerStateRunning)).To(Succeed())
+		candidates["replica-a"].Start(ctx)
+		Eventually(running).Should(HaveLen(1))
+		leader := running()[0]
+		follower := "replica-a"
+		if leader == follower {
+			follower = "replica-b"
+		}
+
+		// the leader shuts down
+		candidates[leader].Stop(ctx)
+
+		Eventually(running).Should(Equal([]string{follower}))
+		Consistently(running, 500*time.Millisecond).Should(Equal([]string{follower}))
+		Expect(leases.GetLeader(ctx)).To(Equal(follower))
+	})
+
+	It("should apply the settings saved on another replica to the leader", func() {
+		leader, follower := electLeader()
+
+		batchSize := 42
+		Expect(states.UpdateSettings(ctx, models.SchedulerSettings{BatchSize: &batchSize})).To(Succeed())
+
+		Eventually(func() int { return inners[leader].Status().Settings.BatchSize }).Should(Equal(42))
+
+		// the settings follow the leadership
+		candidates[leader].Stop(ctx)
+		Eventually(running).Should(Equal([]string{follower}))
+		Expect(inners[follower].Status().Settings.BatchSize).To(Equal(42))
+	})
+
+	It("should stop the leader when the scheduler is stopped on another replica", func() {
+		_, _ = electLeader()
+
+		Expect(states.SetDesiredState(ctx, models.SchedulerStateStopped)).To(Succeed())
+
+		Eventually(running).Should(BeEmpty())
+		Expect(leases.GetLeader(ctx)).To(BeEmpty())
+
+		Expect(states.SetDesiredState(ctx, models.SchedulerStateRunning)).To(Succeed())
+		Eventually(running).Should(HaveLen(1))
+	})
+})
@@ -1,10 +1,12 @@
 package services
 
 import (
-	"sort"
+	"context"
 	"time"
 
+	"go-template-microservice/internal/models"
 	"go-template-microservice/internal/repository"
+	"go-template-microservice/internal/resources/request"
 	"go-template-microservice/internal/resources/response"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,9 +14,19 @@ import (
 )
 
 type MessageService interface {
-	StartScheduler(c *fiber.Ctx)
-	StopScheduler(c *fiber.Ctx)
+	StartScheduler(ctx context.Context)
+	StopScheduler(ctx context.Context)
+	RestoreScheduler(ctx context.Context, autoStart bool)
+	SchedulerStatus() response.SchedulerStatus
+	DispatchMessages(ctx context.Context, batchSize int) response.DispatchSummary
+	UpdateScheduler(ctx context.Context, req request.UpdateSchedulerRequest) response.SchedulerStatus
 	ListSentMessages(ctx *fiber.Ctx, limit int) ([]response.SentMessageResponse, error)
+	CreateMessage(req request.CreateMessageRequest) (*models.Message, error)
+	CreateMessages(reqs []request.CreateMessageRequest) ([]models.Message, error)
+	GetMessage(messageID int64) (*models.Message, error)
+	CancelMessage(messageID int64, req request.CancelMessageRequest) (*models.Message, error)
+	UpdateMessage(messageID int64, req request.UpdateMessageRequest) (*models.Message, error)
+	ListMessages(req request.ListMessagesRequest) ([]models.Message, response.CursorPagination, error)
 }
 
 type sortableMessage struct {
@@ -26,6 +38,7 @@ type messageService struct {
 	repo      repository.MessageRepository
 	cacheRepo repository.MessageCacheRepository
 	scheduler MessageScheduler
+	stateRepo repository.SchedulerStateRepository
 	logger    *logrus.Logger
 }
 
@@ -33,98 +46,270 @@ func NewMessageService(
 	repo repository.MessageRepository,
 	cacheRepo repository.MessageCacheRepository,
 	scheduler MessageScheduler,
+	stateRepo repository.SchedulerStateRepository,
 	logger *logrus.Logger,
 ) MessageService {
 	return &messageService{
 		repo:      repo,
 		cacheRepo: cacheRepo,
 		scheduler: scheduler,
+		stateRepo: stateRepo,
 		logger:    logger,
 	}
 }
 
-func (s *messageService) StartScheduler(c *fiber.Ctx) {
-	s.scheduler.Start(c)
+// StartScheduler starts the scheduler and saves running as the desired state so restarts resume it
+func (s *messageService) StartScheduler(ctx context.Context) {
+	s.saveDesiredState(ctx, models.SchedulerStateRunning)
+	s.scheduler.Start(ctx)
 }
 
-func (s *messageService) StopScheduler(c *fiber.Ctx) {
-	s.scheduler.Stop(c)
+// StopScheduler stops the scheduler and saves stopped as the desired state so restarts keep it stopped
+func (s *messageService) StopScheduler(ctx context.Context) {
+	s.saveDesiredState(ctx, models.SchedulerStateStopped)
+	s.scheduler.Stop(ctx)
 }
 
-// ListSentMessages returns sent messages sorted by sentAt descending (newest first).
-// It combines results from cache and database, ensuring consistent ordering.
-func (s *messageService) ListSentMessages(ctx *fiber.Ctx, limit int) ([]response.SentMessageResponse, error) {
-	var sortable []sortableMessage
+// RestoreScheduler brings the scheduler back to the state and settings operators last set through
+// the API, autoStart only decides when no state was saved yet or it can't be read
+func (s *messageService) RestoreScheduler(ctx context.Context, autoStart bool) {
+	state := models.SchedulerStateStopped
+	if autoStart {
+		state = models.SchedulerStateRunning
+	}
 
-	// First try to get from cache
-	cachedMessages, err := s.cacheRepo.GetAllSentMessages(ctx.Context(), limit)
-	if err != nil {
-		s.logger.WithError(err).Warn("Failed to get messages from cache, falling back to database")
-	} else if len(cachedMessages) > 0 {
-		s.logger.WithField("count", len(cachedMessages)).Debug("Retrieved messages from cache")
-
-		for _, cached := range cachedMessages {
-			sortable = append(sortable, sortableMessage{
-				response: response.SentMessageResponse{
-					MessageID:         cached.MessageID,
-					ExternalMessageID: cached.ExternalMessageID,
-					To:                cached.To,
-					Content:           cached.Content,
-					SentAt:            cached.SentAt.Format("2006-01-02 15:04:05"),
-				},
-				sentAt: cached.SentAt,
-			})
+	if s.stateRepo != nil {
+		if settings, err := s.stateRepo.GetSettings(ctx); err != nil {
+			s.logger.WithError(err).Warn("Failed to read scheduler settings, keeping the configured ones")
+		} else {
+			s.scheduler.Reconfigure(NewSchedulerSettings(settings))
+		}
+
+		saved, err := s.stateRepo.GetDesiredState(ctx)
+		if err != nil {
+			s.logger.WithError(err).WithField("autoStart", autoStart).Warn("Failed to read desired scheduler state, falling back to auto start setting")
+		} else if saved != "" {
+			state = saved
 		}
 	}
 
-	if len(sortable) >= limit {
-		// Sort by sentAt descending before returning
-		sort.Slice(sortable, func(i, j int) bool {
-			return sortable[i].sentAt.After(sortable[j].sentAt)
-		})
-		responses := make([]response.SentMessageResponse, limit)
-		for i := 0; i < limit; i++ {
-			responses[i] = sortable[i].response
+	s.logger.WithField("state", state).Info("Restoring message scheduler state")
+	if state == models.SchedulerStateRunning {
+		s.scheduler.Start(ctx)
+	}
+}
+
+func (s *messageService) saveDesiredState(ctx context.Context, state models.SchedulerState) {
+	if s.stateRepo == nil {
+		return
+	}
+	if err := s.stateRepo.SetDesiredState(ctx, state); err != nil {
+		s.logger.WithError(err).WithField("state", state).Warn("Failed to save desired scheduler state, it won't survive a restart")
+	}
+}
+
+func (s *messageService) SchedulerStatus() response.SchedulerStatus {
+	return s.scheduler.Status()
+}
+
+// DispatchMessages sends one batch right away, independently of whether the scheduler is running
+func (s *messageService) DispatchMessages(ctx context.Context, batchSize int) response.DispatchSummary {
+	return s.scheduler.RunOnce(ctx, batchSize)
+}
+
+// UpdateScheduler applies the requested settings to the scheduler and returns its new status. The
+// settings are saved as well so they survive restarts and reach the leader when it runs elsewhere.
+func (s *messageService) UpdateScheduler(ctx context.Context, req request.UpdateSchedulerRequest) response.SchedulerStatus {
+	settings := models.SchedulerSettings{
+		IntervalInSeconds: req.IntervalInSeconds,
+		BatchSize:         req.BatchSize,
+		Concurrency:       req.Concurrency,
+	}
+	if s.stateRepo != nil {
+		if err := s.stateRepo.UpdateSettings(ctx, settings); err != nil {
+			s.logger.WithError(err).Warn("Failed to save scheduler settings, they only apply to this replica until it restarts")
 		}
-		return responses, nil
 	}
 
-	remainingLimit := limit - len(sortable)
-	s.logger.WithField("remainingLimit", remainingLimit).Debug("Fetching additional messages from database")
+	s.scheduler.Reconfigure(NewSchedulerSettings(settings))
+	return s.scheduler.Status()
+}
 
-	dbMessages, err := s.repo.GetSentMessages(remainingLimit)
+// CreateMessage enqueues a new message with PENDING status so the scheduler can pick it up
+func (s *messageService) CreateMessage(req request.CreateMessageRequest) (*models.Message, error) {
+	message, err := s.repo.CreateMessage(newMessage(req))
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to get sent messages from database")
-		// If we have some cached responses, return them instead of failing
-		if len(sortable) > 0 {
-			// Sort by sentAt descending before returning
-			sort.Slice(sortable, func(i, j int) bool {
-				return sortable[i].sentAt.After(sortable[j].sentAt)
-			})
-			responses := make([]response.SentMessageResponse, len(sortable))
-			for i, s := range sortable {
-				responses[i] = s.response
-			}
-			return responses, nil
+		s.logger.WithError(err).Error("Failed to create message")
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// CreateMessages enqueues all given messages atomically, the result keeps the order of the requests
+func (s *messageService) CreateMessages(reqs []request.CreateMessageRequest) ([]models.Message, error) {
+	if len(reqs) == 0 {
+		return []models.Message{}, nil
+	}
+
+	messages := make([]models.Message, len(reqs))
+	for i, req := range reqs {
+		messages[i] = newMessage(req)
+	}
+
+	created, err := s.repo.CreateMessages(messages)
+	if err != nil {
+		s.logger.WithError(err).WithField("count", len(reqs)).Error("Failed to create messages batch")
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func newMessage(req request.CreateMessageRequest) models.Message {
+	return models.Message{
+		To:          req.To,
+		Content:     req.Content,
+		ScheduledAt: req.ScheduledAt,
+		ExpiresAt:   req.ExpiresAt,
+		Priority:    models.Priority(req.Priority),
+		Category:    req.Category,
+	}
+}
+
+func (s *messageService) GetMessage(messageID int64) (*models.Message, error) {
+	return s.repo.GetMessage(messageID)
+}
+
+// CancelMessage withdraws a PENDING message, messages being sent or already in a final status
+// are refused with repository.ErrMessageNotPending. The cancellation is guarded by the updated_at
+// of the request, or the one just read when the request doesn't carry it.
+func (s *messageService) CancelMessage(messageID int64, req request.CancelMessageRequest) (*models.Message, error) {
+	msg, err := s.repo.GetMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedAt := msg.UpdatedAt
+	if req.UpdatedAt != "" {
+		updatedAt, err = time.Parse(time.RFC3339, req.UpdatedAt)
+		if err != nil {
+			return nil, err
 		}
+	}
+
+	cancelled, err := s.repo.CancelMessage(messageID, updatedAt)
+	if err != nil {
+		s.logger.WithError(err).WithField("messageID", messageID).Warn("Failed to cancel message")
 		return nil, err
 	}
+	return cancelled, nil
+}
 
-	// Build a set of already cached message IDs to avoid duplicates
-	cachedIDs := make(map[int64]bool)
-	for _, s := range sortable {
-		cachedIDs[s.response.MessageID] = true
+// UpdateMessage edits the recipient or content of a PENDING message. The update is guarded by
+// the updated_at of the request, or the one just read when the request doesn't carry it.
+func (s *messageService) UpdateMessage(messageID int64, req request.UpdateMessageRequest) (*models.Message, error) {
+	msg, err := s.repo.GetMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if req.To == nil && req.Content == nil {
+		return msg, nil
 	}
 
-	// Add messages from DB that aren't already in cache
-	for _, msg := range dbMessages {
-		if cachedIDs[msg.ID] {
-			continue // Skip duplicates
+	updatedAt := msg.UpdatedAt
+	if req.UpdatedAt != nil {
+		updatedAt = *req.UpdatedAt
+	}
+
+	updated, err := s.repo.UpdatePendingMessage(messageID, updatedAt, repository.MessageChanges{To: req.To, Content: req.Content})
+	if err != nil {
+		s.logger.WithError(err).WithField("messageID", messageID).Warn("Failed to update message")
+		return nil, err
+	}
+	return updated, nil
+}
+
+// ListMessages returns a page of messages matching the request together with the cursor of the
+// next page. One row more than requested is fetched to tell whether there is a next page.
+func (s *messageService) ListMessages(req request.ListMessagesRequest) ([]models.Message, response.CursorPagination, error) {
+	const defaultLimit = 10
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	pagination := response.CursorPagination{Limit: limit}
+
+	filter := repository.MessageFilter{
+		To:      req.To,
+		Content: req.Content,
+		SortBy:  repository.SortByCreatedAt,
+		Limit:   limit + 1,
+	}
+	if req.Sort != "" {
+		filter.SortBy = repository.MessageSortField(req.Sort)
+	}
+	for _, status := range req.Status {
+		filter.Statuses = append(filter.Statuses, models.Status(status))
+	}
+
+	for _, bound := range []struct {
+		value  string
+		target **time.Time
+	}{
+		{req.CreatedFrom, &filter.CreatedFrom},
+		{req.CreatedTo, &filter.CreatedTo},
+		{req.SentFrom, &filter.SentFrom},
+		{req.SentTo, &filter.SentTo},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			return nil, pagination, err
+		}
+		*bound.target = &t
+	}
+
+	if req.Cursor != "" {
+		after, err := decodeCursor(filter.SortBy, req.Cursor)
+		if err != nil {
+			return nil, pagination, err
 		}
+		filter.After = after
+	}
+
+	messages, err := s.repo.ListMessages(filter)
+	if err != nil {
+		return nil, pagination, err
+	}
+
+	if len(messages) > limit {
+		messages = messages[:limit]
+		pagination.HasMore = true
+		pagination.NextCursor = encodeCursor(filter.SortBy, messages[limit-1])
+	}
+	if messages == nil {
+		messages = []models.Message{}
+	}
+	return messages, pagination, nil
+}
 
-		sortable = append(sortable, sortableMessage{
+// ListSentMessages returns the latest sent messages, newest first. The cache index already
+// yields a true top-N by sent time, so a full page from the cache is returned as is. A partial
+// page means older messages only live in the database: the top-N of the database is fetched
+// and merged with the cached page.
+func (s *messageService) ListSentMessages(ctx *fiber.Ctx, limit int) ([]response.SentMessageResponse, error) {
+	var cached []sortableMessage
+	cachedMessages, err := s.cacheRepo.GetAllSentMessages(ctx.Context(), limit)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get messages from cache, falling back to database")
+	}
+	for _, msg := range cachedMessages {
+		cached = append(cached, sortableMessage{
 			response: response.SentMessageResponse{
-				MessageID:         msg.ID,
+				MessageID:         msg.MessageID,
 				ExternalMessageID: msg.ExternalMessageID,
 				To:                msg.To,
 				Content:           msg.Content,
@@ -132,21 +317,76 @@ func (s *messageService) ListSentMessages(ctx *fiber.Ctx, limit int) ([]response
 			},
 			sentAt: msg.SentAt,
 		})
+	}
 
-		if len(sortable) >= limit {
-			break
+	if len(cached) >= limit {
+		return responsesOf(cached[:limit]), nil
+	}
+
+	s.logger.WithField("cached", len(cached)).Debug("Cache holds a partial page, merging with the database")
+	dbMessages, err := s.repo.GetSentMessages(limit)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get sent messages from database")
+		// the cached page is still correctly ordered, return it instead of failing
+		if len(cached) > 0 {
+			return responsesOf(cached), nil
+		}
+		return nil, err
+	}
+
+	stored := make([]sortableMessage, len(dbMessages))
+	for i, msg := range dbMessages {
+		stored[i] = sortableMessage{
+			response: response.SentMessageResponse{
+				MessageID:         msg.ID,
+				ExternalMessageID: msg.ExternalMessageID,
+				To:                msg.To,
+				Content:           msg.Content,
+				SentAt:            msg.SentAt.Format("2006-01-02 15:04:05"),
+			},
+			sentAt: msg.SentAt,
 		}
 	}
 
-	// Sort combined results by sentAt descending (newest first)
-	sort.Slice(sortable, func(i, j int) bool {
-		return sortable[i].sentAt.After(sortable[j].sentAt)
-	})
+	return responsesOf(mergeSentMessages(limit, cached, stored)), nil
+}
+
+// mergeSentMessages merges lists sorted by sent time descending into their top limit entries,
+// a message present in several lists is kept once
+func mergeSentMessages(limit int, lists ...[]sortableMessage) []sortableMessage {
+	merged := make([]sortableMessage, 0, limit)
+	seen := make(map[int64]bool)
+	heads := make([]int, len(lists))
+	for len(merged) < limit {
+		next := -1
+		for i, list := range lists {
+			// skip entries already taken from another list
+			for heads[i] < len(list) && seen[list[heads[i]].response.MessageID] {
+				heads[i]++
+			}
+			if heads[i] == len(list) {
+				continue
+			}
+			if next == -1 || list[heads[i]].sentAt.After(lists[next][heads[next]].sentAt) {
+				next = i
+			}
+		}
+		if next == -1 {
+			break
+		}
 
-	responses := make([]response.SentMessageResponse, len(sortable))
-	for i, s := range sortable {
-		responses[i] = s.response
+		msg := lists[next][heads[next]]
+		seen[msg.response.MessageID] = true
+		merged = append(merged, msg)
+		heads[next]++
 	}
+	return merged
+}
 
-	return responses, nil
+func responsesOf(messages []sortableMessage) []response.SentMessageResponse {
+	responses := make([]response.SentMessageResponse, len(messages))
+	for i, msg := range messages {
+		responses[i] = msg.response
+	}
+	return responses
 }
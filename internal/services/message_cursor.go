@@ -0,0 +1,49 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-template-microservice/internal/models"
+	"go-template-microservice/internal/repository"
+)
+
+// ErrInvalidCursor is returned for cursors that weren't issued by the listing or that belong
+// to a listing sorted by another field
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor returns the opaque cursor of the page following the given message. It carries the
+// sort field so a cursor can't be replayed against a listing sorted differently.
+func encodeCursor(sortBy repository.MessageSortField, msg models.Message) string {
+	position := msg.CreatedAt
+	if sortBy == repository.SortBySentAt {
+		position = msg.SentAt
+	}
+	raw := fmt.Sprintf("%s|%d|%d", sortBy, position.UnixNano(), msg.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(sortBy repository.MessageSortField, cursor string) (*repository.MessageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || parts[0] != string(sortBy) {
+		return nil, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &repository.MessageCursor{Time: time.Unix(0, nanos), ID: id}, nil
+}
@@ -2,48 +2,196 @@ package services
 
 import (
 	"context"
+	"errors"
 	"go-template-microservice/internal/models"
 	"go-template-microservice/internal/repository"
+	"go-template-microservice/internal/resources/response"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 )
 
 type MessageScheduler interface {
-	Start(c *fiber.Ctx)
-	Stop(c *fiber.Ctx)
+	// Start launches the scheduling loop on the scheduler's own context, ctx only scopes the call
+	Start(ctx context.Context)
+	// Stop ends the loop and waits for in-flight sends. When ctx is done before they finish
+	// the sends are cancelled and their messages are returned to the queue.
+	Stop(ctx context.Context)
+	Status() response.SchedulerStatus
+	// RunOnce runs a single tick right away on the scheduler's own context, waiting for a tick
+	// of the loop in progress to finish first. A batchSize of zero uses the configured one.
+	RunOnce(ctx context.Context, batchSize int) response.DispatchSummary
+	// Reconfigure changes the tunables of the scheduler without restarting it, in-flight work
+	// finishes with the previous values
+	Reconfigure(settings SchedulerSettings)
+}
+
+// minDrainPollInterval keeps a draining scheduler with a very high send rate from polling the
+// database in a busy loop
+const minDrainPollInterval = 100 * time.Millisecond
+
+// SchedulerOptions holds the tunables of the message scheduler
+type SchedulerOptions struct {
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// WorkerID identifies this scheduler as the owner of the messages it claims
+	WorkerID string
+	// LeaseDuration is how long a claimed message stays reserved for this worker
+	LeaseDuration time.Duration
+	// SendTimeout is the longest a single send can take. A message whose lease ends sooner is
+	// returned to the queue instead of being sent and claims are capped at what the workers can
+	// send within a lease. Zero disables both.
+	SendTimeout time.Duration
+	// Concurrency is the number of messages sent in parallel within a batch
+	Concurrency int
+	// Drain keeps claiming batches within a tick as long as they come back full instead of
+	// sending one batch per interval, the pace is then set by the rate limited sender
+	Drain bool
+	// RatePerSecond is the rate the sender is limited to, zero when it isn't limited. Claims are
+	// capped at what can be sent at this rate within a lease so no claimed message outlives its
+	// lease waiting for a token, and a draining scheduler that emptied the queue polls at this
+	// rate instead of waiting for the next interval.
+	RatePerSecond float64
+	// LowPriorityShare is the percentage of each batch reserved for low priority messages
+	// so they keep moving while higher priority lanes are busy
+	LowPriorityShare int
+	// SendingSchedule restricts sending to its windows, ticks outside of them send nothing
+	// but messages of the exempt categories. A nil schedule allows sending at any time.
+	SendingSchedule *SendingSchedule
+	// ExemptCategories are the message categories sent outside the sending windows, e.g. otp
+	ExemptCategories []string
+	// CircuitBreaker is the breaker guarding the sender, ticks send nothing while it is open.
+	// A nil breaker is always closed.
+	CircuitBreaker *CircuitBreaker
+}
+
+// SchedulerSettings holds the scheduler tunables that can be changed at runtime,
+// nil fields keep their current value
+type SchedulerSettings struct {
+	Interval    *time.Duration
+	BatchSize   *int
+	Concurrency *int
+}
+
+// NewSchedulerSettings converts the settings saved in the state store
+func NewSchedulerSettings(saved models.SchedulerSettings) SchedulerSettings {
+	settings := SchedulerSettings{
+		BatchSize:   saved.BatchSize,
+		Concurrency: saved.Concurrency,
+	}
+	if saved.IntervalInSeconds != nil {
+		interval := time.Duration(*saved.IntervalInSeconds) * time.Second
+		settings.Interval = &interval
+	}
+	return settings
 }
 
 type messageScheduler struct {
+	// ctx is the root context of every loop, cancelling it aborts in-flight sends and ends the loop
+	ctx context.Context
+
 	repo   repository.MessageRepository
 	sender MessageSenderService
 	cache  repository.MessageCacheRepository
 
-	interval  time.Duration
-	bacthSize int
+	maxAttempts   int
+	backoff       ExponentialBackoff
+	workerID      string
+	leaseDuration time.Duration
+	drain         bool
+	lowShare      int
+	schedule      *SendingSchedule
+	exempt        []string
+	breaker       *CircuitBreaker
+
+	// maxClaim caps the batch size so a batch can be sent within its lease, zero means no cap
+	maxClaim int
+	// sendTimeout is the lease a message needs left to be sent, zero sends it whatever is left
+	sendTimeout time.Duration
+	// pollInterval replaces the interval while draining and the queue is empty, zero disables it
+	pollInterval time.Duration
+	// pollSoon is set when the last draining tick emptied the queue or stopped early for a manual
+	// tick, the loop then ticks again after pollInterval
+	pollSoon atomic.Bool
+	// manualTicks counts the RunOnce calls waiting for or running a tick, a draining tick of the
+	// loop stops after its current batch so they don't queue behind it
+	manualTicks atomic.Int32
+
+	mu sync.Mutex
+	// interval, batchSize and concurrency can be changed through Reconfigure and are guarded by mu
+	interval    time.Duration
+	batchSize   int
+	concurrency int
+	// resetChan tells the loop to reset its ticker after the interval changed
+	resetChan chan struct{}
+	running   bool
+	stopChan  chan struct{}
+	doneChan  chan struct{}
+	cancelRun context.CancelFunc
+	status    response.SchedulerStatus
 
-	mu       sync.Mutex
-	running  bool
-	stopChan chan struct{}
-	doneChan chan struct{}
+	// tickMu serializes the ticks of the loop with the ones triggered through RunOnce
+	tickMu sync.Mutex
 
 	logger *logrus.Logger
 }
 
-func NewMessageScheduler(repo repository.MessageRepository, sender MessageSenderService, cache repository.MessageCacheRepository, interval time.Duration, batchSize int, logger *logrus.Logger) MessageScheduler {
+func NewMessageScheduler(ctx context.Context, repo repository.MessageRepository, sender MessageSenderService, cache repository.MessageCacheRepository, opts SchedulerOptions, logger *logrus.Logger) MessageScheduler {
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		maxClaim     int
+		pollInterval time.Duration
+	)
+	// a message is never left less than half its lease to be sent
+	sendTimeout := min(opts.SendTimeout, opts.LeaseDuration/2)
+	if opts.RatePerSecond > 0 {
+		if opts.LeaseDuration > 0 {
+			maxClaim = max(int(opts.RatePerSecond*opts.LeaseDuration.Seconds()), 1)
+		}
+		if opts.Drain {
+			pollInterval = max(time.Duration(float64(time.Second)/opts.RatePerSecond), minDrainPollInterval)
+		}
+	}
+
 	return &messageScheduler{
-		repo:      repo,
-		sender:    sender,
-		cache:     cache,
-		interval:  interval,
-		bacthSize: batchSize,
-		logger:    logger,
+		ctx:           ctx,
+		repo:          repo,
+		sender:        sender,
+		cache:         cache,
+		interval:      opts.Interval,
+		batchSize:     opts.BatchSize,
+		maxAttempts:   maxAttempts,
+		backoff:       NewExponentialBackoff(opts.BackoffBase, opts.BackoffMax),
+		workerID:      opts.WorkerID,
+		leaseDuration: opts.LeaseDuration,
+		sendTimeout:   sendTimeout,
+		concurrency:   concurrency,
+		drain:         opts.Drain,
+		maxClaim:      maxClaim,
+		pollInterval:  pollInterval,
+		lowShare:      opts.LowPriorityShare,
+		schedule:      opts.SendingSchedule,
+		exempt:        opts.ExemptCategories,
+		breaker:       opts.CircuitBreaker,
+		resetChan:     make(chan struct{}, 1),
+		logger:        logger,
 	}
 }
 
-func (s *messageScheduler) Start(c *fiber.Ctx) {
+func (s *messageScheduler) Start(_ context.Context) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -56,73 +204,618 @@ func (s *messageScheduler) Start(c *fiber.Ctx) {
 	s.stopChan = make(chan struct{})
 	s.doneChan = make(chan struct{})
 
-	go s.loop(c.Context())
+	startedAt := time.Now()
+	s.status = response.SchedulerStatus{
+		StartedAt:          &startedAt,
+		LastTickAt:         s.status.LastTickAt,
+		LastTickDurationMs: s.status.LastTickDurationMs,
+		LastTick:           s.status.LastTick,
+	}
+
+	runCtx, cancel := context.WithCancel(s.ctx)
+	s.cancelRun = cancel
+	go func() {
+		defer cancel()
+		s.loop(runCtx)
+	}()
 }
 
-func (s *messageScheduler) Stop(c *fiber.Ctx) {
+func (s *messageScheduler) Stop(ctx context.Context) {
 	s.mu.Lock()
 	if !s.running {
 		s.mu.Unlock()
 		return
 	}
-	close(s.stopChan)
+	select {
+	case <-s.stopChan:
+	default:
+		close(s.stopChan)
+	}
+	doneChan, cancelRun := s.doneChan, s.cancelRun
+	s.mu.Unlock()
+
+	select {
+	case <-doneChan:
+	case <-ctx.Done():
+		s.logger.WithError(ctx.Err()).Warn("Message scheduler did not stop in time, cancelling in-flight sends")
+		cancelRun()
+		<-doneChan
+	}
+}
+
+// Status returns a snapshot of the scheduler state and its delivery counters
+func (s *messageScheduler) Status() response.SchedulerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.status
+	status.Running = s.running
+	status.SendingWindowOpen = s.schedule.IsOpen(time.Now())
+	if s.breaker != nil {
+		status.CircuitBreaker = string(s.breaker.State())
+	}
+	status.Settings = response.SchedulerSettings{
+		IntervalInSeconds: int(s.interval / time.Second),
+		BatchSize:         s.batchSize,
+		Concurrency:       s.concurrency,
+	}
+	return status
+}
+
+func (s *messageScheduler) Reconfigure(settings SchedulerSettings) {
+	s.mu.Lock()
+	intervalChanged := false
+	if settings.Interval != nil && *settings.Interval > 0 && *settings.Interval != s.interval {
+		s.interval = *settings.Interval
+		intervalChanged = true
+	}
+	if settings.BatchSize != nil && *settings.BatchSize > 0 {
+		s.batchSize = *settings.BatchSize
+	}
+	if settings.Concurrency != nil && *settings.Concurrency > 0 {
+		s.concurrency = *settings.Concurrency
+	}
+	fields := logrus.Fields{
+		"interval":    s.interval,
+		"batchSize":   s.batchSize,
+		"concurrency": s.concurrency,
+	}
 	s.mu.Unlock()
 
-	<-s.doneChan
+	if intervalChanged {
+		select {
+		case s.resetChan <- struct{}{}:
+		default:
+		}
+	}
+	s.logger.WithFields(fields).Info("Message scheduler reconfigured")
+}
+
+// tunables returns the current values of the settings that can be changed at runtime
+func (s *messageScheduler) tunables() (time.Duration, int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.interval, s.batchSize, s.concurrency
 }
 
 func (s *messageScheduler) loop(ctx context.Context) {
 	defer func() {
 		s.mu.Lock()
 		s.running = false
+		s.status.StartedAt = nil
+		s.status.NextTickAt = nil
 		close(s.doneChan)
 		s.mu.Unlock()
 	}()
 
-	ticker := time.NewTicker(s.interval)
+	interval, batchSize, _ := s.tunables()
+	period := interval
+	ticker := time.NewTicker(period)
 	defer ticker.Stop()
+	s.setNextTick(time.Now().Add(period))
 
 	// run immediately on start
-	s.tick(ctx)
+	s.tick(ctx, batchSize, s.drain)
+	period = s.pace(ticker, period, interval)
 
 	for {
 		select {
-		case <-ticker.C:
-			s.tick(ctx)
+		case t := <-ticker.C:
+			interval, batchSize, _ := s.tunables()
+			s.setNextTick(t.Add(period))
+			s.tick(ctx, batchSize, s.drain)
+			period = s.pace(ticker, period, interval)
+		case <-s.resetChan:
+			// the interval changed, the next tick is one new interval away
+			interval, _, _ := s.tunables()
+			period = interval
+			ticker.Reset(period)
+			s.setNextTick(time.Now().Add(period))
 		case <-s.stopChan:
 			return
+		case <-ctx.Done():
+			return
 		}
 	}
 }
 
-func (s *messageScheduler) tick(ctx context.Context) {
-	messages, err := s.repo.GetUnsentMessages(s.bacthSize)
-	if err != nil {
-		s.logger.WithError(err).Error("Failed to retrieve unsent messages")
-		return
+// pace returns the period of the ticker after a tick and resets the ticker when it changed. A
+// draining scheduler that emptied the queue polls at the send rate so new messages don't wait
+// for the next interval, it goes back to the interval as soon as a tick can't empty the queue.
+func (s *messageScheduler) pace(ticker *time.Ticker, period, interval time.Duration) time.Duration {
+	next := interval
+	if s.pollInterval > 0 && s.pollSoon.Load() {
+		next = min(s.pollInterval, interval)
 	}
+	if next != period {
+		ticker.Reset(next)
+		s.setNextTick(time.Now().Add(next))
+	}
+	return next
+}
 
-	for _, msg := range messages {
-		resp, err := s.sender.Send(ctx, msg.To, msg.Content)
+// deliveryOutcome is the result of a single delivery attempt
+type deliveryOutcome int
+
+const (
+	deliverySent deliveryOutcome = iota
+	deliveryRetried
+	deliveryFailed
+	// deliveryAborted means the send was cancelled because the scheduler is shutting down
+	deliveryAborted
+	// deliveryExpired means the message expired before it could be sent and was not sent at all
+	deliveryExpired
+	// deliveryDeferred means the circuit breaker refused the send, the message goes back to the queue
+	deliveryDeferred
+	// deliveryLeaseEnding means the lease would run out before the send completes, the message goes
+	// back to the queue without being sent
+	deliveryLeaseEnding
+	// deliveryLeaseLost means the message was sent but its lease was lost meanwhile, the worker
+	// holding it now records its outcome
+	deliveryLeaseLost
+)
+
+// tickStats counts the delivery outcomes of a tick
+type tickStats struct {
+	sent    atomic.Int64
+	retried atomic.Int64
+	failed  atomic.Int64
+	skipped atomic.Int64
+	expired atomic.Int64
+}
+
+func (t *tickStats) record(outcome deliveryOutcome) {
+	switch outcome {
+	case deliverySent:
+		t.sent.Add(1)
+	case deliveryRetried:
+		t.retried.Add(1)
+	case deliveryFailed:
+		t.failed.Add(1)
+	case deliveryExpired:
+		t.expired.Add(1)
+	case deliveryLeaseLost:
+		t.skipped.Add(1)
+	}
+}
+
+// RunOnce claims and sends a single batch even when the scheduler drains the queue
+func (s *messageScheduler) RunOnce(_ context.Context, batchSize int) response.DispatchSummary {
+	if batchSize <= 0 {
+		_, batchSize, _ = s.tunables()
+	}
+	batchSize = s.claimSize(batchSize)
+
+	s.manualTicks.Add(1)
+	defer s.manualTicks.Add(-1)
+
+	s.logger.WithField("batchSize", batchSize).Info("Running a manual scheduler tick")
+	return response.DispatchSummary{
+		BatchSize:          batchSize,
+		SchedulerTickStats: s.tick(s.ctx, batchSize, false),
+	}
+}
+
+// claimSize caps the batch size at what can be sent within a lease
+func (s *messageScheduler) claimSize(batchSize int) int {
+	if s.maxClaim > 0 {
+		// a bigger batch couldn't be sent at the limited rate before its lease runs out
+		batchSize = min(batchSize, s.maxClaim)
+	}
+	if s.sendTimeout > 0 {
+		// nor by the workers if every send took as long as it may
+		_, _, concurrency := s.tunables()
+		batchSize = min(batchSize, max(concurrency*int(s.leaseDuration/s.sendTimeout), 1))
+	}
+	return batchSize
+}
+
+// tick reaps, expires and sends one batch, or keeps sending batches while they come back full
+// when drain is set
+func (s *messageScheduler) tick(ctx context.Context, batchSize int, drain bool) response.SchedulerTickStats {
+	s.tickMu.Lock()
+	defer s.tickMu.Unlock()
+
+	startedAt := time.Now()
+	stats := &tickStats{}
+	if drain {
+		s.pollSoon.Store(false)
+	}
+	batchSize = s.claimSize(batchSize)
+
+	s.reapExpiredLeases()
+	s.expireMessages(stats)
+	if s.breaker.State() == CircuitOpen {
+		s.logger.Debug("Circuit breaker is open, skipping tick")
+	} else if categories, ok := s.sendingScope(startedAt); ok {
+		s.sendBatches(ctx, batchSize, drain, categories, stats)
+	}
+
+	return s.recordTick(startedAt, stats)
+}
+
+// sendingScope returns the categories a tick at the given time may claim, nil meaning all of
+// them. Outside the sending windows only the exempt categories are claimed, ok is false when
+// there are none and the tick has nothing to do.
+func (s *messageScheduler) sendingScope(now time.Time) (categories []string, ok bool) {
+	if s.schedule.IsOpen(now) {
+		return nil, true
+	}
+	if len(s.exempt) == 0 {
+		s.logger.Debug("Outside of the sending windows, skipping tick")
+		return nil, false
+	}
+	s.logger.WithField("categories", s.exempt).Debug("Outside of the sending windows, sending exempt messages only")
+	return s.exempt, true
+}
+
+// sendBatches claims and dispatches one batch, or keeps going while batches come back full
+// when draining
+func (s *messageScheduler) sendBatches(ctx context.Context, batchSize int, drain bool, categories []string, stats *tickStats) {
+	for {
+		messages, err := s.claimBatch(batchSize, categories)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to claim unsent messages")
+			return
+		}
+
+		paused := s.dispatch(ctx, messages, stats)
+		if !drain || paused || s.stopping() || ctx.Err() != nil {
+			return
+		}
+		if len(messages) < batchSize || s.manualTicks.Load() > 0 {
+			// the queue is empty or a manual tick is waiting, the loop picks up again shortly
+			s.pollSoon.Store(true)
+			return
+		}
+	}
+}
+
+func (s *messageScheduler) recordTick(startedAt time.Time, stats *tickStats) response.SchedulerTickStats {
+	last := response.SchedulerTickStats{
+		Sent:    int(stats.sent.Load()),
+		Retried: int(stats.retried.Load()),
+		Failed:  int(stats.failed.Load()),
+		Skipped: int(stats.skipped.Load()),
+		Expired: int(stats.expired.Load()),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status.LastTickAt = &startedAt
+	s.status.LastTickDurationMs = time.Since(startedAt).Milliseconds()
+	s.status.LastTick = last
+	s.status.SinceStart.Sent += last.Sent
+	s.status.SinceStart.Retried += last.Retried
+	s.status.SinceStart.Failed += last.Failed
+	s.status.SinceStart.Skipped += last.Skipped
+	s.status.SinceStart.Expired += last.Expired
+	return last
+}
+
+func (s *messageScheduler) setNextTick(at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.NextTickAt = &at
+}
+
+// claimBatch fills a batch lane by lane from the highest priority down. The low priority
+// reserve is claimed first so a steady stream of urgent messages can never starve the low
+// lane, slots the reserve leaves unused go to the other lanes as usual. Only messages of the
+// given categories are claimed unless categories is empty.
+func (s *messageScheduler) claimBatch(batchSize int, categories []string) ([]models.Message, error) {
+	var batch []models.Message
+	if reserve := lowPriorityReserve(batchSize, s.lowShare); reserve > 0 {
+		claimed, err := s.claim(models.PriorityLow, reserve, categories)
 		if err != nil {
-			s.logger.WithError(err).WithField("messageID", msg.ID).Error("Failed to send message")
-			continue
-		}
-		sendAt := time.Now()
-		err = s.repo.UpdateMessageStatus(msg.ID, models.StatusSent, &resp.MessageID, &sendAt)
-
-		if s.cache != nil {
-			cacheData := models.SentMessageCache{
-				MessageID:         msg.ID,
-				ExternalMessageID: resp.MessageID,
-				To:                msg.To,
-				Content:           msg.Content,
-				SentAt:            sendAt,
+			return nil, err
+		}
+		batch = append(batch, claimed...)
+	}
+
+	for _, priority := range models.Priorities {
+		remaining := batchSize - len(batch)
+		if remaining <= 0 {
+			break
+		}
+		claimed, err := s.claim(priority, remaining, categories)
+		if err != nil {
+			// keep what was claimed so far, the rest of the lanes wait for the next batch
+			if len(batch) > 0 {
+				s.logger.WithError(err).WithField("priority", priority).Error("Failed to claim messages")
+				break
 			}
-			cacheErr := s.cache.CacheSentMessage(ctx, cacheData)
-			if cacheErr != nil {
-				s.logger.WithError(cacheErr).WithField("messageID", msg.ID).Error("Failed to cache sent message")
+			return nil, err
+		}
+		batch = append(batch, claimed...)
+	}
+
+	sort.SliceStable(batch, func(i, j int) bool {
+		return batch[i].Priority.Rank() < batch[j].Priority.Rank()
+	})
+	return batch, nil
+}
+
+func (s *messageScheduler) claim(priority models.Priority, limit int, categories []string) ([]models.Message, error) {
+	return s.repo.ClaimMessages(repository.ClaimOptions{
+		Owner:         s.workerID,
+		Limit:         limit,
+		LeaseDuration: s.leaseDuration,
+		Priority:      priority,
+		Categories:    categories,
+	})
+}
+
+// lowPriorityReserve turns the low priority share into a number of batch slots, any non-zero
+// share reserves at least one slot and at least one slot is always left for the other lanes
+func lowPriorityReserve(batchSize, sharePercent int) int {
+	if batchSize < 2 || sharePercent <= 0 {
+		return 0
+	}
+	reserve := (batchSize*sharePercent + 99) / 100
+	return min(max(reserve, 1), batchSize-1)
+}
+
+// stopping reports whether Stop has been called on the running loop while a tick is in progress
+func (s *messageScheduler) stopping() bool {
+	s.mu.Lock()
+	running, stopChan := s.running, s.stopChan
+	s.mu.Unlock()
+
+	if !running || stopChan == nil {
+		return false
+	}
+	select {
+	case <-stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// dispatch delivers a claimed batch through a bounded pool of workers and returns once every
+// send has finished. Messages are grouped by recipient and each group is handled by a single
+// worker in claim order, so two messages to the same recipient are never in flight at once.
+// Outcomes are counted into stats. It reports whether the batch was cut short because the
+// webhook rate limited us or the circuit breaker opened.
+func (s *messageScheduler) dispatch(ctx context.Context, messages []models.Message, stats *tickStats) bool {
+	groups := groupByRecipient(messages)
+	if len(groups) == 0 {
+		return false
+	}
+
+	var (
+		wg        sync.WaitGroup
+		paused    atomic.Bool
+		skippedMu sync.Mutex
+		skipped   []models.Message
+	)
+
+	jobs := make(chan []models.Message)
+	_, _, concurrency := s.tunables()
+	for range min(concurrency, len(groups)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range jobs {
+				for i, msg := range group {
+					// the webhook asked us to slow down or we are shutting down,
+					// the rest of the batch waits for the next tick
+					if paused.Load() || ctx.Err() != nil {
+						skippedMu.Lock()
+						skipped = append(skipped, group[i:]...)
+						skippedMu.Unlock()
+						break
+					}
+
+					outcome, err := s.deliver(ctx, msg)
+					if outcome == deliveryAborted || outcome == deliveryDeferred || outcome == deliveryLeaseEnding {
+						if outcome == deliveryDeferred {
+							paused.Store(true)
+						}
+						skippedMu.Lock()
+						skipped = append(skipped, group[i:]...)
+						skippedMu.Unlock()
+						break
+					}
+					stats.record(outcome)
+					var rateLimited *RateLimitedError
+					if errors.As(err, &rateLimited) {
+						paused.Store(true)
+					}
+				}
 			}
+		}()
+	}
+
+	for _, group := range groups {
+		jobs <- group
+	}
+	close(jobs)
+	wg.Wait()
+
+	stats.skipped.Add(int64(len(skipped)))
+	s.releaseClaims(skipped)
+	return paused.Load()
+}
+
+// deliver sends a single message and records the result, the returned error is the send error if any
+func (s *messageScheduler) deliver(ctx context.Context, msg models.Message) (deliveryOutcome, error) {
+	// the message may have expired while it waited for its turn in the batch
+	if msg.Expired(time.Now()) {
+		if err := s.repo.UpdateMessageStatus(msg.ID, s.workerID, models.StatusExpired, nil, nil); err != nil {
+			s.logCompletionError(msg.ID, err, "Failed to mark message as expired")
+		}
+		return deliveryExpired, nil
+	}
+	// the batch took longer than planned, another worker may claim the message once the lease
+	// runs out and must not find it being sent
+	if s.sendTimeout > 0 && msg.LeaseExpiresAt != nil && time.Now().Add(s.sendTimeout).After(*msg.LeaseExpiresAt) {
+		s.logger.WithField("messageID", msg.ID).Warn("Message lease ends before it could be sent, returning it to the queue")
+		return deliveryLeaseEnding, nil
+	}
+
+	route := DeliveryRoute{Category: msg.Category, Priority: msg.Priority}
+	resp, err := s.sender.Send(WithDeliveryRoute(ctx, route), msg.To, msg.Content)
+	if err != nil {
+		if ctx.Err() != nil {
+			// cancelled sends don't count as an attempt, the claim is released by the caller
+			return deliveryAborted, err
+		}
+		if errors.Is(err, ErrCircuitOpen) {
+			return deliveryDeferred, err
+		}
+		return s.handleSendFailure(msg, err), err
+	}
+
+	sendAt := time.Now()
+	if err := s.repo.MarkMessageSent(msg.ID, s.workerID, resp.MessageID, resp.Endpoint, sendAt); err != nil {
+		s.logCompletionError(msg.ID, err, "Failed to mark message as sent")
+		if errors.Is(err, repository.ErrLeaseLost) {
+			return deliveryLeaseLost, nil
+		}
+	}
+
+	if s.cache != nil {
+		cacheData := models.SentMessageCache{
+			MessageID:         msg.ID,
+			ExternalMessageID: resp.MessageID,
+			To:                msg.To,
+			Content:           msg.Content,
+			SentAt:            sendAt,
+		}
+		// the message is already sent, cache it even if the scheduler is being stopped
+		cacheErr := s.cache.CacheSentMessage(context.WithoutCancel(ctx), cacheData)
+		if cacheErr != nil {
+			s.logger.WithError(cacheErr).WithField("messageID", msg.ID).Error("Failed to cache sent message")
 		}
 	}
+
+	return deliverySent, nil
+}
+
+// groupByRecipient splits the batch per recipient, keeping the claim order inside and across groups
+func groupByRecipient(messages []models.Message) [][]models.Message {
+	index := make(map[string]int)
+	var groups [][]models.Message
+	for _, msg := range messages {
+		i, ok := index[msg.To]
+		if !ok {
+			i = len(groups)
+			index[msg.To] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], msg)
+	}
+	return groups
+}
+
+// reapExpiredLeases returns messages of crashed or stuck workers back to the queue
+func (s *messageScheduler) reapExpiredLeases() {
+	if _, err := s.repo.ReleaseExpiredLeases(); err != nil {
+		s.logger.WithError(err).Error("Failed to release expired leases")
+	}
+}
+
+// expireMessages moves stale messages to EXPIRED before anything is claimed so they are never sent
+func (s *messageScheduler) expireMessages(stats *tickStats) {
+	expired, err := s.repo.ExpireMessages()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to expire messages")
+		return
+	}
+	stats.expired.Add(expired)
+}
+
+func (s *messageScheduler) releaseClaims(messages []models.Message) {
+	if len(messages) == 0 {
+		return
+	}
+
+	ids := make([]int64, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+	if err := s.repo.ReleaseClaims(s.workerID, ids); err != nil {
+		s.logger.WithError(err).WithField("count", len(ids)).Error("Failed to release claimed messages")
+	}
+}
+
+// handleSendFailure decides what happens to a message after a failed delivery:
+// permanent rejections are marked FAILED right away, rate limited messages wait for the
+// Retry-After delay and everything else is retried with exponential backoff until the
+// attempts are used up
+func (s *messageScheduler) handleSendFailure(msg models.Message, sendErr error) deliveryOutcome {
+	attempts := msg.Attempts + 1
+	logger := s.logger.WithError(sendErr).WithFields(logrus.Fields{
+		"messageID": msg.ID,
+		"attempts":  attempts,
+	})
+
+	var (
+		permanent   *PermanentError
+		rateLimited *RateLimitedError
+	)
+
+	var delay time.Duration
+	switch {
+	case errors.As(sendErr, &permanent):
+		logger.WithField("statusCode", permanent.StatusCode).Error("Message rejected by webhook, marking as failed")
+		s.markFailed(msg, sendErr)
+		return deliveryFailed
+	case attempts >= s.maxAttempts:
+		logger.Error("Failed to send message, no attempts left")
+		s.markFailed(msg, sendErr)
+		return deliveryFailed
+	case errors.As(sendErr, &rateLimited) && rateLimited.RetryAfter > 0:
+		delay = rateLimited.RetryAfter
+	default:
+		// transient and unclassified errors
+		delay = s.backoff.Delay(attempts)
+	}
+
+	nextAttemptAt := time.Now().Add(delay)
+	logger.WithField("nextAttemptAt", nextAttemptAt).Warn("Failed to send message, retry scheduled")
+	if err := s.repo.RecordSendFailure(msg.ID, s.workerID, models.StatusPending, sendErr.Error(), &nextAttemptAt); err != nil {
+		s.logCompletionError(msg.ID, err, "Failed to schedule message retry")
+	}
+	return deliveryRetried
+}
+
+func (s *messageScheduler) markFailed(msg models.Message, sendErr error) {
+	if err := s.repo.RecordSendFailure(msg.ID, s.workerID, models.StatusFailed, sendErr.Error(), nil); err != nil {
+		s.logCompletionError(msg.ID, err, "Failed to mark message as failed")
+	}
+}
+
+// logCompletionError logs a failed completion write. A lost lease isn't an error of this worker,
+// the message was released or claimed again after the lease expired and its result is left alone.
+func (s *messageScheduler) logCompletionError(messageID int64, err error, msg string) {
+	logger := s.logger.WithError(err).WithField("messageID", messageID)
+	if errors.Is(err, repository.ErrLeaseLost) {
+		logger.Warn("Message lease lost before its result was recorded, leaving it to the current owner")
+		return
+	}
+	logger.Error(msg)
 }
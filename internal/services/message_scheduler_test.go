@@ -1,14 +1,22 @@
 package services_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go-template-microservice/internal/models"
+	"go-template-microservice/internal/repository"
+	"go-template-microservice/internal/resources/response"
 	"go-template-microservice/internal/services"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
 )
 
 var _ = Describe("MessageScheduler", func() {
@@ -32,11 +40,11 @@ var _ = Describe("MessageScheduler", func() {
 
 				sender := services.NewMessageSenderService(server.URL, "test-auth-key", logger)
 
-				msg1, err := messageRepository.CreateMessage("+905551111111", "Test Message 1")
+				msg1, err := messageRepository.CreateMessage(models.Message{To: "+905551111111", Content: "Test Message 1"})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(msg1).NotTo(BeNil())
 
-				msg2, err := messageRepository.CreateMessage("+905552222222", "Test Message 2")
+				msg2, err := messageRepository.CreateMessage(models.Message{To: "+905552222222", Content: "Test Message 2"})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(msg2).NotTo(BeNil())
 
@@ -55,11 +63,11 @@ var _ = Describe("MessageScheduler", func() {
 				// This test verifies the scheduler setup - actual tick processing
 				// is tested via the end-to-end integration tests below
 				scheduler := services.NewMessageScheduler(
+					ctx,
 					messageRepoMock,
 					messageSenderMock,
 					messageCacheMock,
-					1*time.Hour,
-					10,
+					services.SchedulerOptions{Interval: 1 * time.Hour, BatchSize: 10},
 					logger,
 				)
 				Expect(scheduler).NotTo(BeNil())
@@ -67,6 +75,956 @@ var _ = Describe("MessageScheduler", func() {
 		})
 	})
 
+	Describe("Retry Handling", func() {
+		expectClaim := func(messages []models.Message) {
+			messageRepoMock.EXPECT().ReleaseExpiredLeases().Return(int64(0), nil).Times(1)
+			messageRepoMock.EXPECT().ExpireMessages().Return(int64(0), nil).Times(1)
+			messageRepoMock.EXPECT().
+				ClaimMessages(repository.ClaimOptions{Owner: "test-worker", Limit: 10, LeaseDuration: 1 * time.Minute, Priority: models.PriorityHigh}).
+				Return(messages, nil).
+				Times(1)
+			for _, priority := range []models.Priority{models.PriorityNormal, models.PriorityLow} {
+				messageRepoMock.EXPECT().
+					ClaimMessages(repository.ClaimOptions{Owner: "test-worker", Limit: 10 - len(messages), LeaseDuration: 1 * time.Minute, Priority: priority}).
+					Return(nil, nil).
+					Times(1)
+			}
+		}
+
+		newScheduler := func() services.MessageScheduler {
+			return services.NewMessageScheduler(
+				ctx,
+				messageRepoMock,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{
+					Interval:      1 * time.Hour,
+					BatchSize:     10,
+					MaxAttempts:   3,
+					BackoffBase:   1 * time.Minute,
+					BackoffMax:    10 * time.Minute,
+					WorkerID:      "test-worker",
+					LeaseDuration: 1 * time.Minute,
+				},
+				logger,
+			)
+		}
+
+		Context("when sending fails and attempts are left", func() {
+			It("should keep the message PENDING and schedule the next attempt", func() {
+				msg := models.Message{ID: 1, To: "+905551111111", Content: "Retry me", Status: models.StatusPending, Attempts: 0}
+				expectClaim([]models.Message{msg})
+				messageSenderMock.EXPECT().Send(gomock.Any(), msg.To, msg.Content).Return(nil, errors.New("gateway down")).Times(1)
+				messageRepoMock.EXPECT().
+					RecordSendFailure(int64(1), "test-worker", models.StatusPending, "gateway down", gomock.Any()).
+					DoAndReturn(func(_ int64, _ string, _ models.Status, _ string, nextAttemptAt *time.Time) error {
+						Expect(nextAttemptAt).NotTo(BeNil())
+						Expect(*nextAttemptAt).To(BeTemporally(">=", time.Now().Add(29*time.Second)))
+						Expect(*nextAttemptAt).To(BeTemporally("<=", time.Now().Add(1*time.Minute)))
+						return nil
+					}).
+					Times(1)
+
+				scheduler := newScheduler()
+				scheduler.Start(ctx)
+				scheduler.Stop(ctx)
+			})
+		})
+
+		Context("when the last attempt fails", func() {
+			It("should move the message to FAILED", func() {
+				msg := models.Message{ID: 2, To: "+905552222222", Content: "Give up", Status: models.StatusPending, Attempts: 2}
+				expectClaim([]models.Message{msg})
+				messageSenderMock.EXPECT().Send(gomock.Any(), msg.To, msg.Content).Return(nil, errors.New("gateway down")).Times(1)
+				messageRepoMock.EXPECT().
+					RecordSendFailure(int64(2), "test-worker", models.StatusFailed, "gateway down", nil).
+					Return(nil).
+					Times(1)
+
+				scheduler := newScheduler()
+				scheduler.Start(ctx)
+				scheduler.Stop(ctx)
+			})
+		})
+
+		Context("when the webhook permanently rejects the message", func() {
+			It("should move the message to FAILED without retrying", func() {
+				msg := models.Message{ID: 3, To: "+905553333333", Content: "Invalid", Status: models.StatusPending, Attempts: 0}
+				sendErr := &services.PermanentError{StatusCode: http.StatusBadRequest, Err: errors.New("bad request")}
+				expectClaim([]models.Message{msg})
+				messageSenderMock.EXPECT().Send(gomock.Any(), msg.To, msg.Content).Return(nil, sendErr).Times(1)
+				messageRepoMock.EXPECT().
+					RecordSendFailure(int64(3), "test-worker", models.StatusFailed, sendErr.Error(), nil).
+					Return(nil).
+					Times(1)
+
+				scheduler := newScheduler()
+				scheduler.Start(ctx)
+				scheduler.Stop(ctx)
+			})
+		})
+
+		Context("when the webhook rate limits the batch", func() {
+			It("should retry after the requested delay and leave the rest of the batch for later", func() {
+				first := models.Message{ID: 4, To: "+905554444444", Content: "First", Status: models.StatusPending}
+				second := models.Message{ID: 5, To: "+905555555555", Content: "Second", Status: models.StatusPending}
+				sendErr := &services.RateLimitedError{RetryAfter: 5 * time.Minute, Err: errors.New("too many requests")}
+				expectClaim([]models.Message{first, second})
+				messageSenderMock.EXPECT().Send(gomock.Any(), first.To, first.Content).Return(nil, sendErr).Times(1)
+				messageRepoMock.EXPECT().
+					RecordSendFailure(int64(4), "test-worker", models.StatusPending, sendErr.Error(), gomock.Any()).
+					DoAndReturn(func(_ int64, _ string, _ models.Status, _ string, nextAttemptAt *time.Time) error {
+						Expect(*nextAttemptAt).To(BeTemporally("~", time.Now().Add(5*time.Minute), 5*time.Second))
+						return nil
+					}).
+					Times(1)
+				messageRepoMock.EXPECT().ReleaseClaims("test-worker", []int64{5}).Return(nil).Times(1)
+
+				scheduler := newScheduler()
+				scheduler.Start(ctx)
+				scheduler.Stop(ctx)
+
+				Expect(scheduler.Status().LastTick).To(Equal(response.SchedulerTickStats{Retried: 1, Skipped: 1}))
+			})
+		})
+
+		Context("when the lease expired before the failure is recorded", func() {
+			It("should leave the message to its current owner", func() {
+				msg := models.Message{ID: 6, To: "+905556666666", Content: "Slow", Status: models.StatusPending}
+				expectClaim([]models.Message{msg})
+				messageSenderMock.EXPECT().Send(gomock.Any(), msg.To, msg.Content).Return(nil, errors.New("gateway down")).Times(1)
+				messageRepoMock.EXPECT().
+					RecordSendFailure(int64(6), "test-worker", models.StatusPending, "gateway down", gomock.Any()).
+					Return(repository.ErrLeaseLost).
+					Times(1)
+
+				scheduler := newScheduler()
+				scheduler.Start(ctx)
+				scheduler.Stop(ctx)
+
+				Expect(scheduler.Status().LastTick).To(Equal(response.SchedulerTickStats{Retried: 1}))
+			})
+		})
+	})
+
+	Describe("Message Expiry", func() {
+		It("should expire stale messages instead of sending them", func() {
+			expiredAt := time.Now().Add(-1 * time.Minute)
+			stale, err := messageRepository.CreateMessage(models.Message{To: "+905551111111", Content: "Stale OTP", ExpiresAt: &expiredAt})
+			Expect(err).NotTo(HaveOccurred())
+			validUntil := time.Now().Add(10 * time.Minute)
+			_, err = messageRepository.CreateMessage(models.Message{To: "+905552222222", Content: "Fresh OTP", ExpiresAt: &validUntil})
+			Expect(err).NotTo(HaveOccurred())
+
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), "+905552222222", "Fresh OTP").
+				Return(&response.WebhookResponse{MessageID: "ext-fresh"}, nil).
+				Times(1)
+
+			scheduler := services.NewMessageScheduler(
+				ctx,
+				messageRepository,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{Interval: 1 * time.Hour, BatchSize: 10, MaxAttempts: 3, WorkerID: "expiry-worker", LeaseDuration: 1 * time.Minute},
+				logger,
+			)
+
+			summary := scheduler.RunOnce(ctx, 0)
+			Expect(summary.SchedulerTickStats).To(Equal(response.SchedulerTickStats{Sent: 1, Expired: 1}))
+			Expect(scheduler.Status().SinceStart.Expired).To(Equal(1))
+
+			var status models.Status
+			err = sqliteInst.Database().QueryRow("SELECT status FROM messages WHERE id = ?", stale.ID).Scan(&status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(models.StatusExpired))
+		})
+
+		It("should expire a claimed message whose expiry passed before its turn", func() {
+			expiredAt := time.Now().Add(-1 * time.Second)
+			msg := models.Message{ID: 7, To: "+905551111111", Content: "Late OTP", Status: models.StatusSending, ExpiresAt: &expiredAt}
+
+			messageRepoMock.EXPECT().ReleaseExpiredLeases().Return(int64(0), nil).Times(1)
+			messageRepoMock.EXPECT().ExpireMessages().Return(int64(0), nil).Times(1)
+			messageRepoMock.EXPECT().
+				ClaimMessages(gomock.Any()).
+				DoAndReturn(func(opts repository.ClaimOptions) ([]models.Message, error) {
+					if opts.Priority == models.PriorityNormal {
+						return []models.Message{msg}, nil
+					}
+					return nil, nil
+				}).
+				Times(3)
+			messageRepoMock.EXPECT().UpdateMessageStatus(int64(7), "expiry-worker", models.StatusExpired, nil, nil).Return(nil).Times(1)
+			messageSenderMock.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			scheduler := services.NewMessageScheduler(
+				ctx,
+				messageRepoMock,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{Interval: 1 * time.Hour, BatchSize: 10, MaxAttempts: 3, WorkerID: "expiry-worker", LeaseDuration: 1 * time.Minute},
+				logger,
+			)
+
+			summary := scheduler.RunOnce(ctx, 0)
+			Expect(summary.SchedulerTickStats).To(Equal(response.SchedulerTickStats{Expired: 1}))
+		})
+	})
+
+	Describe("Leases", func() {
+		expectClaim := func(messages []models.Message) {
+			messageRepoMock.EXPECT().ReleaseExpiredLeases().Return(int64(0), nil).Times(1)
+			messageRepoMock.EXPECT().ExpireMessages().Return(int64(0), nil).Times(1)
+			messageRepoMock.EXPECT().
+				ClaimMessages(gomock.Any()).
+				DoAndReturn(func(opts repository.ClaimOptions) ([]models.Message, error) {
+					if opts.Priority == models.PriorityNormal {
+						return messages, nil
+					}
+					return nil, nil
+				}).
+				Times(3)
+		}
+
+		newScheduler := func(concurrency int) services.MessageScheduler {
+			return services.NewMessageScheduler(
+				ctx,
+				messageRepoMock,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{Interval: 1 * time.Hour, BatchSize: 100, MaxAttempts: 3, WorkerID: "lease-worker", LeaseDuration: 20 * time.Second, SendTimeout: 5 * time.Second, Concurrency: concurrency},
+				logger,
+			)
+		}
+
+		It("should return a message whose lease ends before it could be sent to the queue", func() {
+			held := time.Now().Add(20 * time.Second)
+			ending := time.Now().Add(2 * time.Second)
+			messages := []models.Message{
+				{ID: 1, To: "+905551111111", Content: "Held", Status: models.StatusSending, LeaseExpiresAt: &held},
+				{ID: 2, To: "+905552222222", Content: "Ending", Status: models.StatusSending, LeaseExpiresAt: &ending},
+			}
+			expectClaim(messages)
+			messageSenderMock.EXPECT().Send(gomock.Any(), "+905551111111", "Held").Return(&response.WebhookResponse{MessageID: "ext-1"}, nil).Times(1)
+			messageRepoMock.EXPECT().MarkMessageSent(int64(1), "lease-worker", "ext-1", "", gomock.Any()).Return(nil).Times(1)
+			messageRepoMock.EXPECT().ReleaseClaims("lease-worker", []int64{2}).Return(nil).Times(1)
+
+			summary := newScheduler(1).RunOnce(ctx, 0)
+			Expect(summary.SchedulerTickStats).To(Equal(response.SchedulerTickStats{Sent: 1, Skipped: 1}))
+		})
+
+		It("should not count a message whose lease was lost while it was sent as sent", func() {
+			held := time.Now().Add(20 * time.Second)
+			msg := models.Message{ID: 3, To: "+905553333333", Content: "Slow", Status: models.StatusSending, LeaseExpiresAt: &held}
+			expectClaim([]models.Message{msg})
+			messageSenderMock.EXPECT().Send(gomock.Any(), msg.To, msg.Content).Return(&response.WebhookResponse{MessageID: "ext-3"}, nil).Times(1)
+			messageRepoMock.EXPECT().MarkMessageSent(int64(3), "lease-worker", "ext-3", "", gomock.Any()).Return(repository.ErrLeaseLost).Times(1)
+
+			summary := newScheduler(1).RunOnce(ctx, 0)
+			Expect(summary.SchedulerTickStats).To(Equal(response.SchedulerTickStats{Skipped: 1}))
+		})
+
+		It("should not claim more than the workers can send within a lease", func() {
+			var limits []int
+			messageRepoMock.EXPECT().ReleaseExpiredLeases().Return(int64(0), nil).Times(2)
+			messageRepoMock.EXPECT().ExpireMessages().Return(int64(0), nil).Times(2)
+			messageRepoMock.EXPECT().ClaimMessages(gomock.Any()).DoAndReturn(func(opts repository.ClaimOptions) ([]models.Message, error) {
+				limits = append(limits, opts.Limit)
+				return nil, nil
+			}).Times(6)
+
+			scheduler := newScheduler(2)
+			// two workers fit four sends of up to 5s each in a 20s lease
+			Expect(scheduler.RunOnce(ctx, 0).BatchSize).To(Equal(8))
+
+			concurrency := 3
+			scheduler.Reconfigure(services.SchedulerSettings{Concurrency: &concurrency})
+			Expect(scheduler.RunOnce(ctx, 0).BatchSize).To(Equal(12))
+			Expect(limits).To(Equal([]int{8, 8, 8, 12, 12, 12}))
+		})
+	})
+
+	Describe("Concurrent Dispatch", func() {
+		It("should send in parallel while never overlapping messages to the same recipient", func() {
+			var messages []models.Message
+			for i := 0; i < 8; i++ {
+				messages = append(messages, models.Message{
+					ID:      int64(i + 1),
+					To:      fmt.Sprintf("+90555000000%d", i%4),
+					Content: fmt.Sprintf("Concurrent %d", i),
+					Status:  models.StatusSending,
+				})
+			}
+
+			var (
+				mu             sync.Mutex
+				inFlight       int
+				maxInFlight    int
+				perRecipient   = map[string]int{}
+				recipientClash bool
+				sentOrder      = map[string][]int64{}
+			)
+
+			messageRepoMock.EXPECT().ReleaseExpiredLeases().Return(int64(0), nil).Times(1)
+			messageRepoMock.EXPECT().ExpireMessages().Return(int64(0), nil).Times(1)
+			messageRepoMock.EXPECT().ClaimMessages(gomock.Any()).Return(messages, nil).Times(1)
+			messageRepoMock.EXPECT().MarkMessageSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(8)
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, to, content string) (*response.WebhookResponse, error) {
+					mu.Lock()
+					inFlight++
+					perRecipient[to]++
+					if perRecipient[to] > 1 {
+						recipientClash = true
+					}
+					maxInFlight = max(maxInFlight, inFlight)
+					for _, msg := range messages {
+						if msg.Content == content {
+							sentOrder[to] = append(sentOrder[to], msg.ID)
+						}
+					}
+					mu.Unlock()
+
+					time.Sleep(20 * time.Millisecond)
+
+					mu.Lock()
+					inFlight--
+					perRecipient[to]--
+					mu.Unlock()
+					return &response.WebhookResponse{MessageID: "ext-" + content}, nil
+				}).
+				Times(8)
+
+			scheduler := services.NewMessageScheduler(
+				ctx,
+				messageRepoMock,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{Interval: 1 * time.Hour, BatchSize: 8, Concurrency: 4, MaxAttempts: 3},
+				logger,
+			)
+
+			scheduler.Start(ctx)
+			scheduler.Stop(ctx)
+
+			Expect(recipientClash).To(BeFalse())
+			Expect(maxInFlight).To(BeNumerically(">", 1))
+			Expect(maxInFlight).To(BeNumerically("<=", 4))
+			for _, ids := range sentOrder {
+				Expect(ids).To(HaveLen(2))
+				Expect(ids[0]).To(BeNumerically("<", ids[1]))
+			}
+		})
+	})
+
+	Describe("Draining", func() {
+		It("should keep claiming batches within a tick until a batch comes back partial", func() {
+			full := []models.Message{
+				{ID: 1, To: "+905551111111", Content: "Drain 1"},
+				{ID: 2, To: "+905552222222", Content: "Drain 2"},
+			}
+			partial := []models.Message{
+				{ID: 3, To: "+905553333333", Content: "Drain 3"},
+			}
+
+			lastClaim := make(chan struct{})
+			messageRepoMock.EXPECT().ReleaseExpiredLeases().Return(int64(0), nil).Times(1)
+			messageRepoMock.EXPECT().ExpireMessages().Return(int64(0), nil).Times(1)
+			gomock.InOrder(
+				messageRepoMock.EXPECT().ClaimMessages(gomock.Any()).Return(full, nil),
+				messageRepoMock.EXPECT().ClaimMessages(gomock.Any()).Return(full, nil),
+				messageRepoMock.EXPECT().ClaimMessages(gomock.Any()).Return(partial, nil),
+				messageRepoMock.EXPECT().ClaimMessages(gomock.Any()).Return(nil, nil),
+				messageRepoMock.EXPECT().ClaimMessages(gomock.Any()).DoAndReturn(func(repository.ClaimOptions) ([]models.Message, error) {
+					close(lastClaim)
+					return nil, nil
+				}),
+			)
+			messageRepoMock.EXPECT().MarkMessageSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(5)
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(&response.WebhookResponse{MessageID: "ext-drain"}, nil).
+				Times(5)
+
+			scheduler := services.NewMessageScheduler(
+				ctx,
+				messageRepoMock,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{Interval: 1 * time.Hour, BatchSize: 2, MaxAttempts: 3, Drain: true},
+				logger,
+			)
+
+			scheduler.Start(ctx)
+			Eventually(lastClaim).Should(BeClosed())
+			scheduler.Stop(ctx)
+		})
+
+		It("should not claim more than the rate allows within a lease", func() {
+			var limits []int
+			messageRepoMock.EXPECT().ReleaseExpiredLeases().Return(int64(0), nil).Times(1)
+			messageRepoMock.EXPECT().ExpireMessages().Return(int64(0), nil).Times(1)
+			messageRepoMock.EXPECT().ClaimMessages(gomock.Any()).DoAndReturn(func(opts repository.ClaimOptions) ([]models.Message, error) {
+				limits = append(limits, opts.Limit)
+				return nil, nil
+			}).Times(3)
+
+			scheduler := services.NewMessageScheduler(
+				ctx,
+				messageRepoMock,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{Interval: 1 * time.Hour, BatchSize: 100, MaxAttempts: 3, LeaseDuration: 3 * time.Second, Drain: true, RatePerSecond: 2},
+				logger,
+			)
+
+			summary := scheduler.RunOnce(ctx, 0)
+			Expect(summary.BatchSize).To(Equal(6))
+			Expect(limits).To(Equal([]int{6, 6, 6}))
+		})
+
+		It("should send a single batch on a manual tick", func() {
+			full := []models.Message{
+				{ID: 1, To: "+905551111111", Content: "Manual 1"},
+				{ID: 2, To: "+905552222222", Content: "Manual 2"},
+				{ID: 3, To: "+905553333333", Content: "Manual 3"},
+			}
+			messageRepoMock.EXPECT().ReleaseExpiredLeases().Return(int64(0), nil).Times(1)
+			messageRepoMock.EXPECT().ExpireMessages().Return(int64(0), nil).Times(1)
+			messageRepoMock.EXPECT().ClaimMessages(gomock.Any()).DoAndReturn(func(opts repository.ClaimOptions) ([]models.Message, error) {
+				Expect(opts.Limit).To(Equal(3))
+				return full, nil
+			}).Times(1)
+			messageRepoMock.EXPECT().MarkMessageSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(3)
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(&response.WebhookResponse{MessageID: "ext-manual"}, nil).
+				Times(3)
+
+			scheduler := services.NewMessageScheduler(
+				ctx,
+				messageRepoMock,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{Interval: 1 * time.Hour, BatchSize: 10, MaxAttempts: 3, LeaseDuration: 3 * time.Second, Drain: true, RatePerSecond: 1},
+				logger,
+			)
+
+			// the full batch would keep a draining tick going
+			summary := scheduler.RunOnce(ctx, 5)
+			Expect(summary.BatchSize).To(Equal(3))
+			Expect(summary.Sent).To(Equal(3))
+		})
+
+		It("should poll at the send rate instead of the interval once the queue is empty", func() {
+			var claims atomic.Int32
+			messageRepoMock.EXPECT().ReleaseExpiredLeases().Return(int64(0), nil).AnyTimes()
+			messageRepoMock.EXPECT().ExpireMessages().Return(int64(0), nil).AnyTimes()
+			messageRepoMock.EXPECT().ClaimMessages(gomock.Any()).DoAndReturn(func(repository.ClaimOptions) ([]models.Message, error) {
+				claims.Add(1)
+				return nil, nil
+			}).AnyTimes()
+
+			scheduler := services.NewMessageScheduler(
+				ctx,
+				messageRepoMock,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{Interval: 1 * time.Hour, BatchSize: 2, MaxAttempts: 3, LeaseDuration: 1 * time.Minute, Drain: true, RatePerSecond: 10},
+				logger,
+			)
+
+			scheduler.Start(ctx)
+			// every tick claims the three priority lanes
+			Eventually(claims.Load).WithTimeout(2 * time.Second).Should(BeNumerically(">=", 9))
+			Expect(*scheduler.Status().NextTickAt).To(BeTemporally("<", time.Now().Add(time.Second)))
+			scheduler.Stop(ctx)
+		})
+	})
+
+	Describe("Priority Lanes", func() {
+		var sent []string
+
+		BeforeEach(func() {
+			sent = nil
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, to, content string) (*response.WebhookResponse, error) {
+					sent = append(sent, content)
+					return &response.WebhookResponse{MessageID: "ext-" + content}, nil
+				}).
+				AnyTimes()
+		})
+
+		runTick := func(batchSize, lowShare int) {
+			scheduler := services.NewMessageScheduler(
+				ctx,
+				messageRepository,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{
+					Interval:         1 * time.Hour,
+					BatchSize:        batchSize,
+					MaxAttempts:      3,
+					WorkerID:         "lane-worker",
+					LeaseDuration:    1 * time.Minute,
+					LowPriorityShare: lowShare,
+				},
+				logger,
+			)
+
+			scheduler.Start(ctx)
+			scheduler.Stop(ctx)
+		}
+
+		create := func(priority models.Priority, contents ...string) {
+			for _, content := range contents {
+				_, err := messageRepository.CreateMessage(models.Message{To: "+90555" + content, Content: content, Priority: priority})
+				Expect(err).NotTo(HaveOccurred())
+			}
+		}
+
+		It("should fill the batch from the highest priority first", func() {
+			create(models.PriorityLow, "low-1")
+			create(models.PriorityNormal, "normal-1", "normal-2")
+			create(models.PriorityHigh, "high-1")
+
+			runTick(3, 0)
+
+			Expect(sent).To(Equal([]string{"high-1", "normal-1", "normal-2"}))
+		})
+
+		It("should reserve a share of the batch for low priority messages", func() {
+			create(models.PriorityLow, "low-1", "low-2")
+			create(models.PriorityHigh, "high-1", "high-2", "high-3", "high-4", "high-5")
+
+			runTick(4, 25)
+
+			Expect(sent).To(Equal([]string{"high-1", "high-2", "high-3", "low-1"}))
+		})
+
+		It("should hand unused reserved slots to the other lanes", func() {
+			create(models.PriorityNormal, "normal-1")
+			create(models.PriorityHigh, "high-1", "high-2", "high-3", "high-4", "high-5")
+
+			runTick(4, 25)
+
+			Expect(sent).To(Equal([]string{"high-1", "high-2", "high-3", "high-4"}))
+		})
+	})
+
+	Describe("Sending Windows", func() {
+		var sent []string
+
+		BeforeEach(func() {
+			sent = nil
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, to, content string) (*response.WebhookResponse, error) {
+					sent = append(sent, content)
+					return &response.WebhookResponse{MessageID: "ext-" + content}, nil
+				}).
+				AnyTimes()
+
+			for _, msg := range []models.Message{
+				{To: "+905550000001", Content: "marketing"},
+				{To: "+905550000002", Content: "otp", Category: "otp"},
+			} {
+				_, err := messageRepository.CreateMessage(msg)
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		newScheduler := func(window string, exempt ...string) services.MessageScheduler {
+			// the window covers either the current hour or the opposite one
+			hour := time.Now().UTC().Hour()
+			if window == "closed" {
+				hour = (hour + 12) % 24
+			}
+			schedule, err := services.ParseSendingSchedule([]string{fmt.Sprintf("* %d * * *", hour)}, "UTC")
+			Expect(err).NotTo(HaveOccurred())
+
+			return services.NewMessageScheduler(
+				ctx,
+				messageRepository,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{
+					Interval:         1 * time.Hour,
+					BatchSize:        10,
+					MaxAttempts:      3,
+					WorkerID:         "window-worker",
+					LeaseDuration:    1 * time.Minute,
+					SendingSchedule:  schedule,
+					ExemptCategories: exempt,
+				},
+				logger,
+			)
+		}
+
+		It("should send every message inside the sending windows", func() {
+			scheduler := newScheduler("open", "otp")
+
+			Expect(scheduler.Status().SendingWindowOpen).To(BeTrue())
+			summary := scheduler.RunOnce(ctx, 0)
+			Expect(summary.Sent).To(Equal(2))
+		})
+
+		It("should only send exempt messages outside the sending windows", func() {
+			scheduler := newScheduler("closed", "otp")
+
+			Expect(scheduler.Status().SendingWindowOpen).To(BeFalse())
+			summary := scheduler.RunOnce(ctx, 0)
+			Expect(summary.Sent).To(Equal(1))
+			Expect(sent).To(Equal([]string{"otp"}))
+		})
+
+		It("should send nothing outside the sending windows without exempt categories", func() {
+			scheduler := newScheduler("closed")
+
+			summary := scheduler.RunOnce(ctx, 0)
+			Expect(summary.SchedulerTickStats).To(Equal(response.SchedulerTickStats{}))
+			Expect(sent).To(BeEmpty())
+			Expect(scheduler.Status().LastTickAt).NotTo(BeNil())
+		})
+	})
+
+	Describe("Circuit Breaker", func() {
+		It("should stop dispatching once the circuit opens and keep the rest of the batch pending", func() {
+			first, err := messageRepository.CreateMessage(models.Message{To: "+905551111111", Content: "Breaker first"})
+			Expect(err).NotTo(HaveOccurred())
+			second, err := messageRepository.CreateMessage(models.Message{To: "+905552222222", Content: "Breaker second"})
+			Expect(err).NotTo(HaveOccurred())
+
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), first.To, first.Content).
+				Return(nil, &services.TransientError{StatusCode: http.StatusInternalServerError, Err: errors.New("internal error")}).
+				Times(1)
+
+			breaker := services.NewCircuitBreaker(services.CircuitBreakerOptions{FailureThreshold: 1, CoolDown: time.Hour}, logger)
+			scheduler := services.NewMessageScheduler(
+				ctx,
+				messageRepository,
+				services.NewCircuitBreakerSender(messageSenderMock, breaker, logger),
+				nil,
+				services.SchedulerOptions{
+					Interval:       1 * time.Hour,
+					BatchSize:      10,
+					MaxAttempts:    3,
+					WorkerID:       "breaker-worker",
+					LeaseDuration:  1 * time.Minute,
+					CircuitBreaker: breaker,
+				},
+				logger,
+			)
+			Expect(scheduler.Status().CircuitBreaker).To(Equal(string(services.CircuitClosed)))
+
+			summary := scheduler.RunOnce(ctx, 0)
+			Expect(summary.SchedulerTickStats).To(Equal(response.SchedulerTickStats{Retried: 1, Skipped: 1}))
+			Expect(scheduler.Status().CircuitBreaker).To(Equal(string(services.CircuitOpen)))
+
+			// nothing is claimed while the circuit is open
+			summary = scheduler.RunOnce(ctx, 0)
+			Expect(summary.SchedulerTickStats).To(Equal(response.SchedulerTickStats{}))
+
+			msg, err := messageRepository.GetMessage(second.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msg.Status).To(Equal(models.StatusPending))
+			Expect(msg.Attempts).To(BeZero())
+			Expect(msg.LeaseOwner).To(BeEmpty())
+		})
+	})
+
+	Describe("Status", func() {
+		It("should report the running state and the delivery counters", func() {
+			_, err := messageRepository.CreateMessage(models.Message{To: "+905551111111", Content: "Status sent"})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = messageRepository.CreateMessage(models.Message{To: "+905552222222", Content: "Status rejected"})
+			Expect(err).NotTo(HaveOccurred())
+
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), "+905551111111", "Status sent").
+				Return(&response.WebhookResponse{MessageID: "status-ext-001"}, nil).
+				Times(1)
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), "+905552222222", "Status rejected").
+				Return(nil, &services.PermanentError{StatusCode: http.StatusBadRequest, Err: errors.New("bad request")}).
+				Times(1)
+
+			scheduler := services.NewMessageScheduler(
+				ctx,
+				messageRepository,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{
+					Interval:      1 * time.Hour,
+					BatchSize:     10,
+					MaxAttempts:   3,
+					WorkerID:      "status-worker",
+					LeaseDuration: 1 * time.Minute,
+				},
+				logger,
+			)
+			Expect(scheduler.Status().Running).To(BeFalse())
+
+			scheduler.Start(ctx)
+			Eventually(func() *time.Time { return scheduler.Status().LastTickAt }).ShouldNot(BeNil())
+
+			status := scheduler.Status()
+			Expect(status.Running).To(BeTrue())
+			Expect(status.StartedAt).NotTo(BeNil())
+			Expect(status.NextTickAt).NotTo(BeNil())
+			Expect(*status.NextTickAt).To(BeTemporally("~", status.StartedAt.Add(time.Hour), time.Second))
+
+			scheduler.Stop(ctx)
+
+			status = scheduler.Status()
+			Expect(status.Running).To(BeFalse())
+			Expect(status.NextTickAt).To(BeNil())
+			Expect(status.LastTick).To(Equal(response.SchedulerTickStats{Sent: 1, Failed: 1}))
+			Expect(status.SinceStart).To(Equal(response.SchedulerTickStats{Sent: 1, Failed: 1}))
+		})
+	})
+
+	Describe("RunOnce", func() {
+		newScheduler := func() services.MessageScheduler {
+			return services.NewMessageScheduler(
+				ctx,
+				messageRepository,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{
+					Interval:      1 * time.Hour,
+					BatchSize:     10,
+					MaxAttempts:   3,
+					WorkerID:      "manual-worker",
+					LeaseDuration: 1 * time.Minute,
+				},
+				logger,
+			)
+		}
+
+		It("should send a batch of the requested size while the scheduler is stopped", func() {
+			for _, content := range []string{"Manual 1", "Manual 2", "Manual 3"} {
+				_, err := messageRepository.CreateMessage(models.Message{To: "+905551231231", Content: content})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(&response.WebhookResponse{MessageID: "manual-ext"}, nil).
+				Times(3)
+
+			scheduler := newScheduler()
+
+			summary := scheduler.RunOnce(ctx, 2)
+			Expect(summary).To(Equal(response.DispatchSummary{BatchSize: 2, SchedulerTickStats: response.SchedulerTickStats{Sent: 2}}))
+			Expect(scheduler.Status().Running).To(BeFalse())
+			Expect(scheduler.Status().LastTick.Sent).To(Equal(2))
+
+			summary = scheduler.RunOnce(ctx, 0)
+			Expect(summary).To(Equal(response.DispatchSummary{BatchSize: 10, SchedulerTickStats: response.SchedulerTickStats{Sent: 1}}))
+		})
+
+		It("should wait for the tick of the running scheduler to finish", func() {
+			_, err := messageRepository.CreateMessage(models.Message{To: "+905551231231", Content: "Slow tick"})
+			Expect(err).NotTo(HaveOccurred())
+
+			inFlight := make(chan struct{})
+			release := make(chan struct{})
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), gomock.Any(), "Slow tick").
+				DoAndReturn(func(context.Context, string, string) (*response.WebhookResponse, error) {
+					close(inFlight)
+					<-release
+					return &response.WebhookResponse{MessageID: "slow-ext"}, nil
+				}).
+				Times(1)
+
+			scheduler := newScheduler()
+			scheduler.Start(ctx)
+			defer scheduler.Stop(ctx)
+			Eventually(inFlight).Should(BeClosed())
+
+			done := make(chan response.DispatchSummary, 1)
+			go func() {
+				done <- scheduler.RunOnce(ctx, 0)
+			}()
+
+			Consistently(done, 200*time.Millisecond).ShouldNot(Receive())
+			close(release)
+
+			var summary response.DispatchSummary
+			Eventually(done).Should(Receive(&summary))
+			// the message was already sent by the loop's tick
+			Expect(summary.Sent).To(BeZero())
+		})
+	})
+
+	Describe("Reconfigure", func() {
+		var ticks atomic.Int32
+
+		BeforeEach(func() {
+			ticks.Store(0)
+			messageRepoMock.EXPECT().ReleaseExpiredLeases().DoAndReturn(func() (int64, error) {
+				ticks.Add(1)
+				return 0, nil
+			}).AnyTimes()
+			messageRepoMock.EXPECT().ExpireMessages().Return(int64(0), nil).AnyTimes()
+			messageRepoMock.EXPECT().ClaimMessages(gomock.Any()).Return(nil, nil).AnyTimes()
+		})
+
+		newScheduler := func() services.MessageScheduler {
+			return services.NewMessageScheduler(
+				ctx,
+				messageRepoMock,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{Interval: 1 * time.Hour, BatchSize: 10, Concurrency: 2, MaxAttempts: 3},
+				logger,
+			)
+		}
+
+		It("should report the effective settings and only change the given ones", func() {
+			scheduler := newScheduler()
+			Expect(scheduler.Status().Settings).To(Equal(response.SchedulerSettings{IntervalInSeconds: 3600, BatchSize: 10, Concurrency: 2}))
+
+			batchSize, invalid := 50, 0
+			scheduler.Reconfigure(services.SchedulerSettings{BatchSize: &batchSize, Concurrency: &invalid})
+
+			Expect(scheduler.Status().Settings).To(Equal(response.SchedulerSettings{IntervalInSeconds: 3600, BatchSize: 50, Concurrency: 2}))
+			Expect(scheduler.RunOnce(ctx, 0).BatchSize).To(Equal(50))
+		})
+
+		It("should reset the ticker of the running loop to the new interval", func() {
+			scheduler := newScheduler()
+			scheduler.Start(ctx)
+			defer scheduler.Stop(ctx)
+			Eventually(ticks.Load).Should(BeEquivalentTo(1))
+
+			interval := 1 * time.Second
+			scheduler.Reconfigure(services.SchedulerSettings{Interval: &interval})
+
+			Eventually(func() *time.Time { return scheduler.Status().NextTickAt }).
+				Should(HaveValue(BeTemporally("~", time.Now().Add(interval), 500*time.Millisecond)))
+			Eventually(ticks.Load, 3*time.Second).Should(BeNumerically(">=", 2))
+			Expect(scheduler.Status().Running).To(BeTrue())
+			Expect(scheduler.Status().Settings.IntervalInSeconds).To(Equal(1))
+		})
+	})
+
+	Describe("Scheduler Tick with Real Components", func() {
+		It("should claim pending messages, deliver them and mark them SENT", func() {
+			msg, err := messageRepository.CreateMessage(models.Message{To: "+905557777777", Content: "Scheduled delivery"})
+			Expect(err).NotTo(HaveOccurred())
+
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), msg.To, msg.Content).
+				Return(&response.WebhookResponse{Message: "Accepted", MessageID: "tick-ext-001"}, nil).
+				Times(1)
+
+			scheduler := services.NewMessageScheduler(
+				ctx,
+				messageRepository,
+				messageSenderMock,
+				messageCacheRepository,
+				services.SchedulerOptions{
+					Interval:      1 * time.Hour,
+					BatchSize:     10,
+					MaxAttempts:   3,
+					WorkerID:      "e2e-worker",
+					LeaseDuration: 1 * time.Minute,
+				},
+				logger,
+			)
+
+			scheduler.Start(ctx)
+			scheduler.Stop(ctx)
+
+			sent, err := messageRepository.GetSentMessages(10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sent).To(HaveLen(1))
+			Expect(sent[0].ID).To(Equal(msg.ID))
+			Expect(sent[0].ExternalMessageID).To(Equal("tick-ext-001"))
+			Expect(sent[0].LeaseOwner).To(BeEmpty())
+
+			cached, err := messageCacheRepository.GetAllSentMessages(ctx, 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached).To(HaveLen(1))
+			Expect(cached[0].ExternalMessageID).To(Equal("tick-ext-001"))
+		})
+	})
+
+	Describe("Shutdown", func() {
+		It("should cancel in-flight sends when the stop deadline passes and requeue their messages", func() {
+			msg, err := messageRepository.CreateMessage(models.Message{To: "+905556666666", Content: "Slow delivery"})
+			Expect(err).NotTo(HaveOccurred())
+
+			inFlight := make(chan struct{})
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), msg.To, msg.Content).
+				DoAndReturn(func(sendCtx context.Context, _, _ string) (*response.WebhookResponse, error) {
+					close(inFlight)
+					<-sendCtx.Done()
+					return nil, sendCtx.Err()
+				}).
+				Times(1)
+
+			scheduler := services.NewMessageScheduler(
+				ctx,
+				messageRepository,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{
+					Interval:      1 * time.Hour,
+					BatchSize:     10,
+					MaxAttempts:   3,
+					WorkerID:      "shutdown-worker",
+					LeaseDuration: 1 * time.Minute,
+				},
+				logger,
+			)
+
+			scheduler.Start(ctx)
+			Eventually(inFlight).Should(BeClosed())
+
+			stopCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			scheduler.Stop(stopCtx)
+
+			Expect(scheduler.Status().Running).To(BeFalse())
+			pending, err := messageRepository.GetUnsentMessages(10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(HaveLen(1))
+			Expect(pending[0].ID).To(Equal(msg.ID))
+			Expect(pending[0].Attempts).To(BeZero())
+			Expect(pending[0].LeaseOwner).To(BeEmpty())
+		})
+
+		It("should stop the loop when the root context is cancelled", func() {
+			messageRepoMock.EXPECT().ReleaseExpiredLeases().Return(int64(0), nil).AnyTimes()
+			messageRepoMock.EXPECT().ExpireMessages().Return(int64(0), nil).AnyTimes()
+			messageRepoMock.EXPECT().ClaimMessages(gomock.Any()).Return(nil, nil).AnyTimes()
+
+			rootCtx, cancel := context.WithCancel(ctx)
+			scheduler := services.NewMessageScheduler(
+				rootCtx,
+				messageRepoMock,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{Interval: 1 * time.Hour, BatchSize: 10, MaxAttempts: 3},
+				logger,
+			)
+
+			scheduler.Start(ctx)
+			Expect(scheduler.Status().Running).To(BeTrue())
+
+			cancel()
+			Eventually(func() bool { return scheduler.Status().Running }).Should(BeFalse())
+		})
+	})
+
 	Describe("End-to-End Flow with Real Components", func() {
 		Context("when processing messages through the entire pipeline", func() {
 			It("should create, send, and cache messages correctly", func() {
@@ -75,7 +1033,7 @@ var _ = Describe("MessageScheduler", func() {
 
 				sender := services.NewMessageSenderService(server.URL, "test-auth-key", logger)
 
-				msg, err := messageRepository.CreateMessage("+905559999999", "E2E Test Message")
+				msg, err := messageRepository.CreateMessage(models.Message{To: "+905559999999", Content: "E2E Test Message"})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(msg).NotTo(BeNil())
 				Expect(msg.Status).To(Equal(models.StatusPending))
@@ -91,7 +1049,9 @@ var _ = Describe("MessageScheduler", func() {
 				Expect(resp.MessageID).To(Equal("e2e-ext-001"))
 
 				sentAt := time.Now()
-				err = messageRepository.UpdateMessageStatus(msg.ID, models.StatusSent, &resp.MessageID, &sentAt)
+				_, err = messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "test-worker", Limit: 1, LeaseDuration: time.Minute})
+				Expect(err).NotTo(HaveOccurred())
+				err = messageRepository.UpdateMessageStatus(msg.ID, "test-worker", models.StatusSent, &resp.MessageID, &sentAt)
 				Expect(err).NotTo(HaveOccurred())
 
 				cacheData := models.SentMessageCache{
@@ -129,7 +1089,7 @@ var _ = Describe("MessageScheduler", func() {
 
 				sender := services.NewMessageSenderService(server.URL, "test-auth-key", logger)
 
-				msg, err := messageRepository.CreateMessage("+905558888888", "Failed Message Test")
+				msg, err := messageRepository.CreateMessage(models.Message{To: "+905558888888", Content: "Failed Message Test"})
 				Expect(err).NotTo(HaveOccurred())
 
 				resp, err := sender.Send(ctx, msg.To, msg.Content)
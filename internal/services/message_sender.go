@@ -7,63 +7,132 @@ import (
 	"fmt"
 	"go-template-microservice/internal/resources/response"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// SendTimeout bounds a single delivery attempt of the senders
+const SendTimeout = 5 * time.Second
+
 type MessageSenderService interface {
 	Send(ctx context.Context, to, content string) (*response.WebhookResponse, error)
 }
 
+// webhookEncoder renders the outbound request body and returns it with its content type
+type webhookEncoder func(to, content string) (string, []byte)
+
 type messageSenderService struct {
 	client     *http.Client
 	webHookURL string
-	authKey    string
+	auth       WebhookAuth
+	encode     webhookEncoder
+	signer     *WebhookSigner
 	logger     *logrus.Logger
 }
 
-func NewMessageSenderService(webHookURL, authKey string, logger *logrus.Logger) MessageSenderService {
-	return &messageSenderService{
+// WebhookOption customizes the requests of a webhook sender
+type WebhookOption func(*messageSenderService)
+
+// WithAuth replaces the static x-ins-auth-key header with another authentication strategy
+func WithAuth(auth WebhookAuth) WebhookOption {
+	return func(s *messageSenderService) {
+		s.auth = auth
+	}
+}
+
+// WithSigner signs the body of every request with signer
+func WithSigner(signer *WebhookSigner) WebhookOption {
+	return func(s *messageSenderService) {
+		s.signer = signer
+	}
+}
+
+// NewMessageSenderService sends messages to a webhook accepting a JSON body {"to", "content"}
+func NewMessageSenderService(webHookURL, authKey string, logger *logrus.Logger, opts ...WebhookOption) MessageSenderService {
+	return newWebhookSender(webHookURL, authKey, encodeJSON, logger, opts)
+}
+
+// NewFormWebhookSender sends messages to a webhook accepting an application/x-www-form-urlencoded
+// body with the to and content fields, the response contract is the same as the JSON webhook
+func NewFormWebhookSender(webHookURL, authKey string, logger *logrus.Logger, opts ...WebhookOption) MessageSenderService {
+	return newWebhookSender(webHookURL, authKey, encodeForm, logger, opts)
+}
+
+func newWebhookSender(webHookURL, authKey string, encode webhookEncoder, logger *logrus.Logger, opts []WebhookOption) *messageSenderService {
+	s := &messageSenderService{
 		client: &http.Client{
-			Timeout: 5 * time.Second,
+			Timeout: SendTimeout,
 		},
 		webHookURL: webHookURL,
-		authKey:    authKey,
+		auth:       NewStaticHeaderAuth(DefaultAuthHeader, authKey),
+		encode:     encode,
 		logger:     logger,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-func (s *messageSenderService) Send(ctx context.Context, to, content string) (*response.WebhookResponse, error) {
+func encodeJSON(to, content string) (string, []byte) {
 	body, _ := json.Marshal(map[string]string{
 		"to":      to,
 		"content": content,
 	})
+	return "application/json", body
+}
+
+func encodeForm(to, content string) (string, []byte) {
+	body := url.Values{"to": {to}, "content": {content}}.Encode()
+	return "application/x-www-form-urlencoded", []byte(body)
+}
+
+func (s *messageSenderService) Send(ctx context.Context, to, content string) (*response.WebhookResponse, error) {
+	contentType, body := s.encode(to, content)
 
 	req, err := http.NewRequestWithContext(ctx, "POST", s.webHookURL, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-ins-auth-key", s.authKey)
+	req.Header.Set("Content-Type", contentType)
+	if err := s.auth.Apply(ctx, req); err != nil {
+		s.logger.WithError(err).Error("Failed to authenticate webhook request")
+		return nil, err
+	}
+	if s.signer != nil {
+		// signed on every attempt so the timestamp reflects when the request was actually sent
+		req.Header.Set(s.signer.Header(), s.signer.Sign(body, time.Now()))
+	}
 
 	resp, err := s.client.Do(req)
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to send message")
-		return nil, err
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, &TransientError{Err: err}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusAccepted {
-		logrus.WithField("status_code", resp.StatusCode).Error("Failed to send message, non-202 response")
-		return nil, fmt.Errorf("failed to send message, status code: %d", resp.StatusCode)
+		s.logger.WithField("status_code", resp.StatusCode).Error("Failed to send message, non-202 response")
+		if revocable, ok := s.auth.(revocableAuth); ok && resp.StatusCode == http.StatusUnauthorized {
+			// the credentials went stale, not the message, it is retried with new ones
+			revocable.revoke(req)
+			return nil, &TransientError{StatusCode: resp.StatusCode, Err: fmt.Errorf("webhook rejected the credentials, status code: %d", resp.StatusCode)}
+		}
+		return nil, classifyStatusCode(resp)
 	}
 
 	var wResp response.WebhookResponse
 	if err := json.NewDecoder(resp.Body).Decode(&wResp); err != nil {
-		s.logger.WithError(err).Error("Failed to decode webhook response")
-		return nil, err
+		// the gateway accepted the message, sending it again would deliver it twice
+		s.logger.WithError(err).Warn("Failed to decode webhook response, message accepted without an external ID")
+		wResp = response.WebhookResponse{}
 	}
+	wResp.Endpoint = s.webHookURL
 
 	return &wResp, nil
 }
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TransientError is returned when the webhook could not process the message right now
+// (5xx, timeouts, connection errors). The message should be retried later.
+type TransientError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *TransientError) Error() string {
+	return fmt.Sprintf("transient delivery error: %v", e.Err)
+}
+
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// PermanentError is returned when the webhook rejected the message (4xx).
+// Retrying the same message won't succeed, it should be marked as FAILED.
+type PermanentError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *PermanentError) Error() string {
+	return fmt.Sprintf("permanent delivery error: %v", e.Err)
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimitedError is returned on 429 responses. RetryAfter holds the delay requested
+// by the webhook through the Retry-After header and is zero when the header is missing.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited, retry after %s: %v", e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("rate limited: %v", e.Err)
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return e.Err
+}
+
+// gatewayAnswered reports whether a send error is the gateway's verdict on the message, a
+// rejection or a rate limit. Every other error, transient or unclassified like a failed auth
+// token request, means the gateway may be unhealthy.
+func gatewayAnswered(err error) bool {
+	var (
+		permanent   *PermanentError
+		rateLimited *RateLimitedError
+	)
+	return errors.As(err, &permanent) || errors.As(err, &rateLimited)
+}
+
+// classifyStatusCode wraps a non-202 webhook response into the matching delivery error
+func classifyStatusCode(resp *http.Response) error {
+	err := fmt.Errorf("failed to send message, status code: %d", resp.StatusCode)
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &RateLimitedError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()), Err: err}
+	case resp.StatusCode == http.StatusRequestTimeout:
+		return &TransientError{StatusCode: resp.StatusCode, Err: err}
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return &PermanentError{StatusCode: resp.StatusCode, Err: err}
+	default:
+		return &TransientError{StatusCode: resp.StatusCode, Err: err}
+	}
+}
+
+// parseRetryAfter supports both forms of the Retry-After header, delay in seconds and HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
@@ -2,7 +2,10 @@ package services_test
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
+	"time"
 
 	"go-template-microservice/internal/services"
 
@@ -24,6 +27,7 @@ var _ = Describe("MessageSenderService", func() {
 				Expect(resp).NotTo(BeNil())
 				Expect(resp.MessageID).To(Equal("ext-12345"))
 				Expect(resp.Message).To(Equal("Accepted"))
+				Expect(resp.Endpoint).To(Equal(server.URL))
 			})
 		})
 
@@ -38,6 +42,10 @@ var _ = Describe("MessageSenderService", func() {
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("status code: 400"))
 				Expect(resp).To(BeNil())
+
+				var permanent *services.PermanentError
+				Expect(errors.As(err, &permanent)).To(BeTrue())
+				Expect(permanent.StatusCode).To(Equal(http.StatusBadRequest))
 			})
 
 			It("should return an error for 500 Internal Server Error", func() {
@@ -50,6 +58,44 @@ var _ = Describe("MessageSenderService", func() {
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("status code: 500"))
 				Expect(resp).To(BeNil())
+
+				var transient *services.TransientError
+				Expect(errors.As(err, &transient)).To(BeTrue())
+				Expect(transient.StatusCode).To(Equal(http.StatusInternalServerError))
+			})
+
+			It("should return a rate limited error honoring Retry-After for 429 Too Many Requests", func() {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Retry-After", "30")
+					w.WriteHeader(http.StatusTooManyRequests)
+				}))
+				defer server.Close()
+
+				sender := services.NewMessageSenderService(server.URL, "test-auth-key", logger)
+				resp, err := sender.Send(context.Background(), "+905551234567", "Hello World")
+
+				Expect(err).To(HaveOccurred())
+				Expect(resp).To(BeNil())
+
+				var rateLimited *services.RateLimitedError
+				Expect(errors.As(err, &rateLimited)).To(BeTrue())
+				Expect(rateLimited.RetryAfter).To(Equal(30 * time.Second))
+			})
+
+			It("should accept an HTTP date in Retry-After", func() {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Retry-After", time.Now().Add(2*time.Minute).UTC().Format(http.TimeFormat))
+					w.WriteHeader(http.StatusTooManyRequests)
+				}))
+				defer server.Close()
+
+				sender := services.NewMessageSenderService(server.URL, "test-auth-key", logger)
+				_, err := sender.Send(context.Background(), "+905551234567", "Hello World")
+
+				var rateLimited *services.RateLimitedError
+				Expect(errors.As(err, &rateLimited)).To(BeTrue())
+				Expect(rateLimited.RetryAfter).To(BeNumerically(">", 1*time.Minute))
+				Expect(rateLimited.RetryAfter).To(BeNumerically("<=", 2*time.Minute))
 			})
 		})
 
@@ -60,19 +106,49 @@ var _ = Describe("MessageSenderService", func() {
 
 				Expect(err).To(HaveOccurred())
 				Expect(resp).To(BeNil())
+
+				var transient *services.TransientError
+				Expect(errors.As(err, &transient)).To(BeTrue())
 			})
 		})
 
 		Context("when webhook returns invalid JSON", func() {
-			It("should return a decode error", func() {
+			It("should treat the accepted message as sent without an external ID", func() {
 				server := createMockWebhookServer(http.StatusAccepted, `invalid json`)
 				defer server.Close()
 
 				sender := services.NewMessageSenderService(server.URL, "test-auth-key", logger)
 				resp, err := sender.Send(context.Background(), "+905551234567", "Hello World")
 
-				Expect(err).To(HaveOccurred())
-				Expect(resp).To(BeNil())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp).NotTo(BeNil())
+				Expect(resp.MessageID).To(BeEmpty())
+				Expect(resp.Endpoint).To(Equal(server.URL))
+			})
+		})
+
+		Context("when the webhook expects a form encoded body", func() {
+			It("should post the to and content fields as a form", func() {
+				var contentType, to, content, authKey string
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					contentType = r.Header.Get("Content-Type")
+					authKey = r.Header.Get("x-ins-auth-key")
+					_ = r.ParseForm()
+					to, content = r.PostForm.Get("to"), r.PostForm.Get("content")
+					w.WriteHeader(http.StatusAccepted)
+					_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-form"}`))
+				}))
+				defer server.Close()
+
+				sender := services.NewFormWebhookSender(server.URL, "test-auth-key", logger)
+				resp, err := sender.Send(context.Background(), "+905551234567", "Hello & World")
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.MessageID).To(Equal("ext-form"))
+				Expect(contentType).To(Equal("application/x-www-form-urlencoded"))
+				Expect(authKey).To(Equal("test-auth-key"))
+				Expect(to).To(Equal("+905551234567"))
+				Expect(content).To(Equal("Hello & World"))
 			})
 		})
 	})
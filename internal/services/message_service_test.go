@@ -2,9 +2,13 @@ package services_test
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"go-template-microservice/internal/models"
+	"go-template-microservice/internal/repository"
+	"go-template-microservice/internal/resources/request"
+	"go-template-microservice/internal/resources/response"
 	"go-template-microservice/internal/services"
 
 	"github.com/gofiber/fiber/v2"
@@ -27,21 +31,311 @@ var _ = Describe("MessageService", func() {
 		}
 	})
 
+	Describe("CreateMessage", func() {
+		Context("with a valid request", func() {
+			It("should persist the message as PENDING", func() {
+				service := services.NewMessageService(
+					messageRepository,
+					messageCacheRepository,
+					nil,
+					nil,
+					logger,
+				)
+
+				msg, err := service.CreateMessage(request.CreateMessageRequest{
+					To:      "+905551234567",
+					Content: "Created via service",
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(msg).NotTo(BeNil())
+				Expect(msg.ID).To(BeNumerically(">", 0))
+				Expect(msg.Status).To(Equal(models.StatusPending))
+
+				pending, err := messageRepository.GetUnsentMessages(10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pending).To(HaveLen(1))
+				Expect(pending[0].ID).To(Equal(msg.ID))
+			})
+		})
+
+		Context("when the repository fails", func() {
+			It("should return the error", func() {
+				messageRepoMock.EXPECT().
+					CreateMessage(models.Message{To: "+905551234567", Content: "Hello"}).
+					Return(nil, errors.New("db error")).
+					Times(1)
+
+				service := services.NewMessageService(
+					messageRepoMock,
+					messageCacheMock,
+					nil,
+					nil,
+					logger,
+				)
+
+				msg, err := service.CreateMessage(request.CreateMessageRequest{
+					To:      "+905551234567",
+					Content: "Hello",
+				})
+
+				Expect(err).To(HaveOccurred())
+				Expect(msg).To(BeNil())
+			})
+		})
+	})
+
+	Describe("CreateMessages", func() {
+		It("should create every message of the batch", func() {
+			service := services.NewMessageService(
+				messageRepository,
+				messageCacheRepository,
+				nil,
+				nil,
+				logger,
+			)
+
+			created, err := service.CreateMessages([]request.CreateMessageRequest{
+				{To: "+905551111111", Content: "Bulk 1"},
+				{To: "+905552222222", Content: "Bulk 2"},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(created).To(HaveLen(2))
+			Expect(created[0].Content).To(Equal("Bulk 1"))
+			Expect(created[1].Content).To(Equal("Bulk 2"))
+		})
+
+		It("should not hit the repository for an empty batch", func() {
+			service := services.NewMessageService(
+				messageRepoMock,
+				messageCacheMock,
+				nil,
+				nil,
+				logger,
+			)
+
+			created, err := service.CreateMessages(nil)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(created).To(BeEmpty())
+		})
+	})
+
+	Describe("ListMessages", func() {
+		var service services.MessageService
+
+		BeforeEach(func() {
+			service = services.NewMessageService(messageRepository, messageCacheRepository, nil, nil, logger)
+			for i := 1; i <= 5; i++ {
+				_, err := service.CreateMessage(request.CreateMessageRequest{To: "+905551234567", Content: fmt.Sprintf("Listed %d", i)})
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("should page through every message with the next cursor", func() {
+			var listed []string
+			req := request.ListMessagesRequest{Limit: 2}
+			for page := 0; ; page++ {
+				Expect(page).To(BeNumerically("<", 3))
+				messages, pagination, err := service.ListMessages(req)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pagination.Limit).To(Equal(2))
+				for _, msg := range messages {
+					listed = append(listed, msg.Content)
+				}
+				if !pagination.HasMore {
+					Expect(pagination.NextCursor).To(BeEmpty())
+					break
+				}
+				req.Cursor = pagination.NextCursor
+			}
+
+			Expect(listed).To(Equal([]string{"Listed 5", "Listed 4", "Listed 3", "Listed 2", "Listed 1"}))
+		})
+
+		It("should reject malformed cursors and cursors of another sort field", func() {
+			_, pagination, err := service.ListMessages(request.ListMessagesRequest{Limit: 2})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = service.ListMessages(request.ListMessagesRequest{Cursor: "not-a-cursor"})
+			Expect(err).To(MatchError(services.ErrInvalidCursor))
+
+			_, _, err = service.ListMessages(request.ListMessagesRequest{Sort: "sent_at", Cursor: pagination.NextCursor})
+			Expect(err).To(MatchError(services.ErrInvalidCursor))
+		})
+	})
+
+	Describe("UpdateMessage", func() {
+		It("should guard the update with the updated_at read from the repository", func() {
+			updatedAt := time.Now().Add(-1 * time.Minute)
+			content := "Edited"
+			messageRepoMock.EXPECT().GetMessage(int64(3)).Return(&models.Message{ID: 3, Status: models.StatusPending, UpdatedAt: updatedAt}, nil).Times(1)
+			messageRepoMock.EXPECT().
+				UpdatePendingMessage(int64(3), updatedAt, repository.MessageChanges{Content: &content}).
+				Return(&models.Message{ID: 3, Content: content}, nil).
+				Times(1)
+
+			service := services.NewMessageService(messageRepoMock, messageCacheMock, nil, nil, logger)
+
+			msg, err := service.UpdateMessage(3, request.UpdateMessageRequest{Content: &content})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msg.Content).To(Equal("Edited"))
+		})
+
+		It("should prefer the updated_at sent by the client", func() {
+			clientUpdatedAt := time.Now().Add(-1 * time.Hour)
+			to := "+905559999999"
+			messageRepoMock.EXPECT().GetMessage(int64(3)).Return(&models.Message{ID: 3, Status: models.StatusPending, UpdatedAt: time.Now()}, nil).Times(1)
+			messageRepoMock.EXPECT().
+				UpdatePendingMessage(int64(3), clientUpdatedAt, repository.MessageChanges{To: &to}).
+				Return(nil, repository.ErrMessageModified).
+				Times(1)
+
+			service := services.NewMessageService(messageRepoMock, messageCacheMock, nil, nil, logger)
+
+			_, err := service.UpdateMessage(3, request.UpdateMessageRequest{To: &to, UpdatedAt: &clientUpdatedAt})
+			Expect(err).To(MatchError(repository.ErrMessageModified))
+		})
+	})
+
+	Describe("CancelMessage", func() {
+		It("should cancel a pending message", func() {
+			service := services.NewMessageService(messageRepository, messageCacheRepository, nil, nil, logger)
+			created, err := service.CreateMessage(request.CreateMessageRequest{To: "+905551234567", Content: "Withdraw me"})
+			Expect(err).NotTo(HaveOccurred())
+
+			cancelled, err := service.CancelMessage(created.ID, request.CancelMessageRequest{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cancelled.Status).To(Equal(models.StatusCancelled))
+
+			_, err = service.CancelMessage(created.ID, request.CancelMessageRequest{})
+			Expect(err).To(MatchError(repository.ErrMessageNotPending))
+		})
+
+		It("should refuse the cancellation when the message changed after the client read it", func() {
+			service := services.NewMessageService(messageRepository, messageCacheRepository, nil, nil, logger)
+			created, err := service.CreateMessage(request.CreateMessageRequest{To: "+905551234567", Content: "Withdraw me"})
+			Expect(err).NotTo(HaveOccurred())
+			clientUpdatedAt := created.UpdatedAt.Format(time.RFC3339Nano)
+
+			content := "Edited meanwhile"
+			_, err = service.UpdateMessage(created.ID, request.UpdateMessageRequest{Content: &content})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = service.CancelMessage(created.ID, request.CancelMessageRequest{UpdatedAt: clientUpdatedAt})
+			Expect(err).To(MatchError(repository.ErrMessageModified))
+
+			msg, err := service.GetMessage(created.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msg.Status).To(Equal(models.StatusPending))
+			_, err = service.CancelMessage(created.ID, request.CancelMessageRequest{UpdatedAt: msg.UpdatedAt.Format(time.RFC3339Nano)})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("Scheduler State", func() {
+		var scheduler services.MessageScheduler
+
+		BeforeEach(func() {
+			messageRepoMock.EXPECT().ReleaseExpiredLeases().Return(int64(0), nil).AnyTimes()
+			messageRepoMock.EXPECT().ExpireMessages().Return(int64(0), nil).AnyTimes()
+			messageRepoMock.EXPECT().ClaimMessages(gomock.Any()).Return(nil, nil).AnyTimes()
+			scheduler = services.NewMessageScheduler(
+				ctx,
+				messageRepoMock,
+				messageSenderMock,
+				nil,
+				services.SchedulerOptions{Interval: 1 * time.Hour, BatchSize: 10, MaxAttempts: 3},
+				logger,
+			)
+		})
+
+		AfterEach(func() {
+			scheduler.Stop(ctx)
+		})
+
+		newService := func() services.MessageService {
+			return services.NewMessageService(messageRepoMock, messageCacheMock, scheduler, schedulerStateMock, logger)
+		}
+
+		It("should save the desired state when the scheduler is started and stopped", func() {
+			gomock.InOrder(
+				schedulerStateMock.EXPECT().SetDesiredState(gomock.Any(), models.SchedulerStateRunning).Return(nil),
+				schedulerStateMock.EXPECT().SetDesiredState(gomock.Any(), models.SchedulerStateStopped).Return(nil),
+			)
+			service := newService()
+
+			service.StartScheduler(ctx)
+			Expect(scheduler.Status().Running).To(BeTrue())
+
+			service.StopScheduler(ctx)
+			Expect(scheduler.Status().Running).To(BeFalse())
+		})
+
+		It("should still start the scheduler when the state can't be saved", func() {
+			schedulerStateMock.EXPECT().SetDesiredState(gomock.Any(), models.SchedulerStateRunning).Return(errors.New("redis down"))
+
+			newService().StartScheduler(ctx)
+
+			Expect(scheduler.Status().Running).To(BeTrue())
+		})
+
+		DescribeTable("RestoreScheduler",
+			func(saved models.SchedulerState, readErr error, autoStart, running bool) {
+				schedulerStateMock.EXPECT().GetSettings(gomock.Any()).Return(models.SchedulerSettings{}, nil)
+				schedulerStateMock.EXPECT().GetDesiredState(gomock.Any()).Return(saved, readErr)
+
+				newService().RestoreScheduler(ctx, autoStart)
+
+				Expect(scheduler.Status().Running).To(Equal(running))
+			},
+			Entry("resumes a scheduler left running", models.SchedulerStateRunning, nil, false, true),
+			Entry("keeps a stopped scheduler stopped despite auto start", models.SchedulerStateStopped, nil, true, false),
+			Entry("auto starts when no state was saved", models.SchedulerState(""), nil, true, true),
+			Entry("stays stopped when no state was saved and auto start is off", models.SchedulerState(""), nil, false, false),
+			Entry("falls back to auto start when the state can't be read", models.SchedulerState(""), errors.New("redis down"), true, true),
+		)
+
+		It("should save the changed settings and apply them", func() {
+			batchSize := 25
+			schedulerStateMock.EXPECT().UpdateSettings(gomock.Any(), models.SchedulerSettings{BatchSize: &batchSize}).Return(nil)
+
+			status := newService().UpdateScheduler(ctx, request.UpdateSchedulerRequest{BatchSize: &batchSize})
+
+			Expect(status.Settings).To(Equal(response.SchedulerSettings{IntervalInSeconds: 3600, BatchSize: 25, Concurrency: 1}))
+		})
+
+		It("should restore the saved settings", func() {
+			interval, concurrency := 30, 4
+			schedulerStateMock.EXPECT().GetSettings(gomock.Any()).Return(models.SchedulerSettings{IntervalInSeconds: &interval, Concurrency: &concurrency}, nil)
+			schedulerStateMock.EXPECT().GetDesiredState(gomock.Any()).Return(models.SchedulerStateStopped, nil)
+
+			newService().RestoreScheduler(ctx, false)
+
+			Expect(scheduler.Status().Settings).To(Equal(response.SchedulerSettings{IntervalInSeconds: 30, BatchSize: 10, Concurrency: 4}))
+		})
+	})
+
 	Describe("ListSentMessages", func() {
 		Context("when there are messages in cache and database", func() {
 			It("should set up repositories correctly", func() {
-				msg1, err := messageRepository.CreateMessage("+905551111111", "DB Message 1")
+				msg1, err := messageRepository.CreateMessage(models.Message{To: "+905551111111", Content: "DB Message 1"})
 				Expect(err).NotTo(HaveOccurred())
 				extID1 := "db-ext-1"
 				sentAt1 := time.Now().Add(-2 * time.Hour)
-				err = messageRepository.UpdateMessageStatus(msg1.ID, models.StatusSent, &extID1, &sentAt1)
+				_, err = messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "test-worker", Limit: 1, LeaseDuration: time.Minute})
+				Expect(err).NotTo(HaveOccurred())
+				err = messageRepository.UpdateMessageStatus(msg1.ID, "test-worker", models.StatusSent, &extID1, &sentAt1)
 				Expect(err).NotTo(HaveOccurred())
 
-				msg2, err := messageRepository.CreateMessage("+905552222222", "DB Message 2")
+				msg2, err := messageRepository.CreateMessage(models.Message{To: "+905552222222", Content: "DB Message 2"})
 				Expect(err).NotTo(HaveOccurred())
 				extID2 := "db-ext-2"
 				sentAt2 := time.Now().Add(-1 * time.Hour)
-				err = messageRepository.UpdateMessageStatus(msg2.ID, models.StatusSent, &extID2, &sentAt2)
+				_, err = messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "test-worker", Limit: 1, LeaseDuration: time.Minute})
+				Expect(err).NotTo(HaveOccurred())
+				err = messageRepository.UpdateMessageStatus(msg2.ID, "test-worker", models.StatusSent, &extID2, &sentAt2)
 				Expect(err).NotTo(HaveOccurred())
 
 				cacheData := models.SentMessageCache{
@@ -58,6 +352,7 @@ var _ = Describe("MessageService", func() {
 					messageRepository,
 					messageCacheRepository,
 					nil,
+					nil,
 					logger,
 				)
 
@@ -90,9 +385,9 @@ var _ = Describe("MessageService", func() {
 					Return(cachedMessages, nil).
 					Times(1)
 
-				// Since cache has 2 messages and limit is 10, service will try to get 8 more from DB
+				// The cache holds a partial page, the top 10 of the database are merged in
 				messageRepoMock.EXPECT().
-					GetSentMessages(8).
+					GetSentMessages(10).
 					Return([]models.Message{}, nil).
 					Times(1)
 
@@ -100,6 +395,7 @@ var _ = Describe("MessageService", func() {
 					messageRepoMock,
 					messageCacheMock,
 					nil,
+					nil,
 					logger,
 				)
 
@@ -116,47 +412,78 @@ var _ = Describe("MessageService", func() {
 				Expect(responses[1].MessageID).To(Equal(int64(2))) // -2 hours (older)
 			})
 
-			It("should sort cached messages by sentAt descending", func() {
+			It("should return a full page from the cache without hitting the database", func() {
 				now := time.Now()
-				// Return messages in arbitrary order (simulating Redis SCAN)
+				// the cache index returns the newest messages first
 				cachedMessages := []models.SentMessageCache{
-					{
-						MessageID:         3,
-						ExternalMessageID: "cache-ext-3",
-						To:                "+905553333333",
-						Content:           "Oldest Message",
-						SentAt:            now.Add(-3 * time.Hour), // oldest
-					},
 					{
 						MessageID:         1,
 						ExternalMessageID: "cache-ext-1",
 						To:                "+905551111111",
 						Content:           "Newest Message",
-						SentAt:            now.Add(-1 * time.Hour), // newest
+						SentAt:            now.Add(-1 * time.Hour),
 					},
 					{
 						MessageID:         2,
 						ExternalMessageID: "cache-ext-2",
 						To:                "+905552222222",
 						Content:           "Middle Message",
-						SentAt:            now.Add(-2 * time.Hour), // middle
+						SentAt:            now.Add(-2 * time.Hour),
 					},
 				}
 
 				messageCacheMock.EXPECT().
-					GetAllSentMessages(gomock.Any(), 10).
+					GetAllSentMessages(gomock.Any(), 2).
 					Return(cachedMessages, nil).
 					Times(1)
+				messageRepoMock.EXPECT().GetSentMessages(gomock.Any()).Times(0)
+
+				service := services.NewMessageService(
+					messageRepoMock,
+					messageCacheMock,
+					nil,
+					nil,
+					logger,
+				)
+
+				app := fiber.New()
+				fiberCtx := app.AcquireCtx(&fasthttp.RequestCtx{})
+				defer app.ReleaseCtx(fiberCtx)
+
+				responses, err := service.ListSentMessages(fiberCtx, 2)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(responses).To(HaveLen(2))
+				Expect(responses[0].MessageID).To(Equal(int64(1)))
+				Expect(responses[1].MessageID).To(Equal(int64(2)))
+			})
+
+			It("should merge a partial cache page with newer and duplicate database rows", func() {
+				now := time.Now()
+				cachedMessages := []models.SentMessageCache{
+					{MessageID: 2, ExternalMessageID: "ext-2", Content: "Cached", SentAt: now.Add(-2 * time.Hour)},
+					{MessageID: 4, ExternalMessageID: "ext-4", Content: "Cached", SentAt: now.Add(-4 * time.Hour)},
+				}
+				// the database holds every sent message, including the cached ones
+				dbMessages := []models.Message{
+					{ID: 1, ExternalMessageID: "ext-1", Status: models.StatusSent, SentAt: now.Add(-1 * time.Hour)},
+					{ID: 2, ExternalMessageID: "ext-2", Status: models.StatusSent, SentAt: now.Add(-2 * time.Hour)},
+					{ID: 3, ExternalMessageID: "ext-3", Status: models.StatusSent, SentAt: now.Add(-3 * time.Hour)},
+				}
 
+				messageCacheMock.EXPECT().
+					GetAllSentMessages(gomock.Any(), 3).
+					Return(cachedMessages, nil).
+					Times(1)
 				messageRepoMock.EXPECT().
-					GetSentMessages(7).
-					Return([]models.Message{}, nil).
+					GetSentMessages(3).
+					Return(dbMessages, nil).
 					Times(1)
 
 				service := services.NewMessageService(
 					messageRepoMock,
 					messageCacheMock,
 					nil,
+					nil,
 					logger,
 				)
 
@@ -164,13 +491,12 @@ var _ = Describe("MessageService", func() {
 				fiberCtx := app.AcquireCtx(&fasthttp.RequestCtx{})
 				defer app.ReleaseCtx(fiberCtx)
 
-				responses, err := service.ListSentMessages(fiberCtx, 10)
+				responses, err := service.ListSentMessages(fiberCtx, 3)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(responses).To(HaveLen(3))
-				// Should be sorted by sentAt descending
-				Expect(responses[0].MessageID).To(Equal(int64(1))) // newest (-1h)
-				Expect(responses[1].MessageID).To(Equal(int64(2))) // middle (-2h)
-				Expect(responses[2].MessageID).To(Equal(int64(3))) // oldest (-3h)
+				Expect(responses[0].MessageID).To(Equal(int64(1)))
+				Expect(responses[1].MessageID).To(Equal(int64(2)))
+				Expect(responses[2].MessageID).To(Equal(int64(3)))
 			})
 
 			It("should sort combined cache and DB messages by sentAt descending", func() {
@@ -212,7 +538,7 @@ var _ = Describe("MessageService", func() {
 					Times(1)
 
 				messageRepoMock.EXPECT().
-					GetSentMessages(9).
+					GetSentMessages(10).
 					Return(dbMessages, nil).
 					Times(1)
 
@@ -220,6 +546,7 @@ var _ = Describe("MessageService", func() {
 					messageRepoMock,
 					messageCacheMock,
 					nil,
+					nil,
 					logger,
 				)
 
@@ -264,6 +591,7 @@ var _ = Describe("MessageService", func() {
 					messageRepoMock,
 					messageCacheMock,
 					nil,
+					nil,
 					logger,
 				)
 
@@ -292,6 +620,7 @@ var _ = Describe("MessageService", func() {
 					messageRepoMock,
 					messageCacheMock,
 					nil,
+					nil,
 					logger,
 				)
 
@@ -309,7 +638,7 @@ var _ = Describe("MessageService", func() {
 	Describe("Integration: Full Message Flow", func() {
 		Context("when a message goes through the entire lifecycle", func() {
 			It("should correctly transition from pending to sent to cached", func() {
-				msg, err := messageRepository.CreateMessage("+905559999999", "Lifecycle Test Message")
+				msg, err := messageRepository.CreateMessage(models.Message{To: "+905559999999", Content: "Lifecycle Test Message"})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(msg).NotTo(BeNil())
 				Expect(msg.Status).To(Equal(models.StatusPending))
@@ -321,7 +650,9 @@ var _ = Describe("MessageService", func() {
 
 				externalID := "lifecycle-ext-001"
 				sentAt := time.Now()
-				err = messageRepository.UpdateMessageStatus(msg.ID, models.StatusSent, &externalID, &sentAt)
+				_, err = messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "test-worker", Limit: 1, LeaseDuration: time.Minute})
+				Expect(err).NotTo(HaveOccurred())
+				err = messageRepository.UpdateMessageStatus(msg.ID, "test-worker", models.StatusSent, &externalID, &sentAt)
 				Expect(err).NotTo(HaveOccurred())
 
 				unsentMsgs, err = messageRepository.GetUnsentMessages(10)
@@ -359,10 +690,10 @@ var _ = Describe("MessageService", func() {
 			It("should handle batch processing correctly", func() {
 				messages := make([]*models.Message, 5)
 				for i := 0; i < 5; i++ {
-					msg, err := messageRepository.CreateMessage(
-						"+90555000000"+string(rune('0'+i)),
-						"Batch Message "+string(rune('A'+i)),
-					)
+					msg, err := messageRepository.CreateMessage(models.Message{
+						To:      "+90555000000" + string(rune('0'+i)),
+						Content: "Batch Message " + string(rune('A'+i)),
+					})
 					Expect(err).NotTo(HaveOccurred())
 					messages[i] = msg
 				}
@@ -371,10 +702,12 @@ var _ = Describe("MessageService", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(unsentMsgs).To(HaveLen(5))
 
+				_, err = messageRepository.ClaimMessages(repository.ClaimOptions{Owner: "test-worker", Limit: len(messages), LeaseDuration: time.Minute})
+				Expect(err).NotTo(HaveOccurred())
 				for i, msg := range messages {
 					externalID := "batch-ext-" + string(rune('0'+i))
 					sentAt := time.Now()
-					err = messageRepository.UpdateMessageStatus(msg.ID, models.StatusSent, &externalID, &sentAt)
+					err = messageRepository.UpdateMessageStatus(msg.ID, "test-worker", models.StatusSent, &externalID, &sentAt)
 					Expect(err).NotTo(HaveOccurred())
 
 					cacheData := models.SentMessageCache{
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"go-template-microservice/internal/resources/response"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitedSender puts a token bucket in front of another sender. The bucket is shared by
+// every scheduler worker so the total outbound rate never exceeds the downstream quota.
+type rateLimitedSender struct {
+	sender  MessageSenderService
+	limiter *rate.Limiter
+	logger  *logrus.Logger
+}
+
+// NewRateLimitedSender allows ratePerSecond sends on average with bursts of up to burst messages
+func NewRateLimitedSender(sender MessageSenderService, ratePerSecond float64, burst int, logger *logrus.Logger) MessageSenderService {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &rateLimitedSender{
+		sender:  sender,
+		limiter: rate.NewLimiter(rate.Limit(ratePerSecond), burst),
+		logger:  logger,
+	}
+}
+
+func (s *rateLimitedSender) Send(ctx context.Context, to, content string) (*response.WebhookResponse, error) {
+	if err := s.limiter.Wait(ctx); err != nil {
+		s.logger.WithError(err).Warn("Rate limiter wait aborted")
+		return nil, err
+	}
+
+	return s.sender.Send(ctx, to, content)
+}
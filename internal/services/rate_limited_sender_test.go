@@ -0,0 +1,70 @@
+package services_test
+
+import (
+	"context"
+	"time"
+
+	"go-template-microservice/internal/resources/response"
+	"go-template-microservice/internal/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("RateLimitedSender", func() {
+	Describe("Send", func() {
+		It("should not exceed the configured rate after the burst is used up", func() {
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(&response.WebhookResponse{MessageID: "ext"}, nil).
+				Times(5)
+
+			sender := services.NewRateLimitedSender(messageSenderMock, 20, 1, logger)
+
+			start := time.Now()
+			for i := 0; i < 5; i++ {
+				_, err := sender.Send(context.Background(), "+905551234567", "Hello")
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			// the first message uses the burst token, the other four wait 50ms each
+			Expect(time.Since(start)).To(BeNumerically(">=", 190*time.Millisecond))
+		})
+
+		It("should let a burst through without waiting", func() {
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(&response.WebhookResponse{MessageID: "ext"}, nil).
+				Times(5)
+
+			sender := services.NewRateLimitedSender(messageSenderMock, 1, 5, logger)
+
+			start := time.Now()
+			for i := 0; i < 5; i++ {
+				_, err := sender.Send(context.Background(), "+905551234567", "Hello")
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(time.Since(start)).To(BeNumerically("<", 100*time.Millisecond))
+		})
+
+		It("should give up without sending when the context is cancelled while waiting", func() {
+			messageSenderMock.EXPECT().
+				Send(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(&response.WebhookResponse{MessageID: "ext"}, nil).
+				Times(1)
+
+			sender := services.NewRateLimitedSender(messageSenderMock, 0.1, 1, logger)
+			_, err := sender.Send(context.Background(), "+905551234567", "Hello")
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			resp, err := sender.Send(ctx, "+905551234567", "Hello")
+			Expect(err).To(HaveOccurred())
+			Expect(resp).To(BeNil())
+		})
+	})
+})
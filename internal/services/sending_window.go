@@ -0,0 +1,261 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SendingSchedule tells whether messages may be sent at a given time. It is made of windows
+// that are either cron expressions matching the allowed minutes ("* 9-20 * * 1-5") or days of
+// the week with a time range ("mon-fri 09:00-21:00"), evaluated in the schedule's timezone.
+// A nil schedule or one without windows is always open.
+type SendingSchedule struct {
+	location *time.Location
+	windows  []sendingWindow
+}
+
+type sendingWindow interface {
+	contains(t time.Time) bool
+}
+
+// ParseSendingSchedule builds a schedule from window specs in the given IANA timezone,
+// an empty timezone means UTC
+func ParseSendingSchedule(specs []string, timezone string) (*SendingSchedule, error) {
+	location := time.UTC
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sending timezone %q: %w", timezone, err)
+		}
+		location = loc
+	}
+
+	schedule := &SendingSchedule{location: location}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		var (
+			window sendingWindow
+			err    error
+		)
+		if fields := strings.Fields(spec); len(fields) == 5 {
+			window, err = parseCronWindow(fields)
+		} else {
+			window, err = parseDayWindow(fields)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid sending window %q: %w", spec, err)
+		}
+		schedule.windows = append(schedule.windows, window)
+	}
+	return schedule, nil
+}
+
+// IsOpen reports whether t falls into one of the windows
+func (s *SendingSchedule) IsOpen(t time.Time) bool {
+	if s == nil || len(s.windows) == 0 {
+		return true
+	}
+
+	t = t.In(s.location)
+	for _, window := range s.windows {
+		if window.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// dayWindow is open on the given days between start and end, both in minutes since midnight.
+// A range ending before it starts runs overnight and belongs to the day it starts on.
+type dayWindow struct {
+	days       [7]bool
+	start, end int
+}
+
+func (w dayWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	if w.start < w.end {
+		return w.days[day] && minute >= w.start && minute < w.end
+	}
+	previous := (day + 6) % 7
+	return (w.days[day] && minute >= w.start) || (w.days[previous] && minute < w.end)
+}
+
+// parseDayWindow parses "<days> <HH:MM>-<HH:MM>" where days is "*" or a comma separated list
+// of days and day ranges such as "mon-fri,sun"
+func parseDayWindow(fields []string) (sendingWindow, error) {
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("expected \"<days> <HH:MM>-<HH:MM>\" or a cron expression")
+	}
+
+	var window dayWindow
+	if fields[0] == "*" {
+		window.days = [7]bool{true, true, true, true, true, true, true}
+	} else {
+		for _, part := range strings.Split(strings.ToLower(fields[0]), ",") {
+			from, to, isRange := strings.Cut(part, "-")
+			first, ok := weekdays[from]
+			if !ok {
+				return nil, fmt.Errorf("unknown day %q", from)
+			}
+			last := first
+			if isRange {
+				if last, ok = weekdays[to]; !ok {
+					return nil, fmt.Errorf("unknown day %q", to)
+				}
+			}
+			// ranges may wrap around the end of the week, e.g. fri-mon
+			for day := first; ; day = (day + 1) % 7 {
+				window.days[day] = true
+				if day == last {
+					break
+				}
+			}
+		}
+	}
+
+	from, to, ok := strings.Cut(fields[1], "-")
+	if !ok {
+		return nil, fmt.Errorf("expected a time range like 09:00-21:00")
+	}
+	var err error
+	if window.start, err = parseClock(from, false); err != nil {
+		return nil, err
+	}
+	if window.end, err = parseClock(to, true); err != nil {
+		return nil, err
+	}
+	if window.start == window.end {
+		return nil, fmt.Errorf("time range %q is empty", fields[1])
+	}
+	return window, nil
+}
+
+// parseClock turns HH:MM into minutes since midnight, 24:00 is only accepted as an end time
+func parseClock(value string, end bool) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err == nil {
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if end && value == "24:00" {
+		return 24 * 60, nil
+	}
+	return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+}
+
+// cronWindow is open during every minute matched by a standard five field cron expression
+type cronWindow struct {
+	minutes, hours, daysOfMonth, months, daysOfWeek []bool
+	// like cron, when both day fields are restricted a day matching either of them is allowed
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+func (w cronWindow) contains(t time.Time) bool {
+	if !w.minutes[t.Minute()] || !w.hours[t.Hour()] || !w.months[t.Month()] {
+		return false
+	}
+
+	dayOfMonth, dayOfWeek := w.daysOfMonth[t.Day()], w.daysOfWeek[t.Weekday()]
+	if w.anyDayOfMonth || w.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+func parseCronWindow(fields []string) (sendingWindow, error) {
+	var (
+		window cronWindow
+		err    error
+	)
+	if window.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if window.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if window.daysOfMonth, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if window.months, err = parseCronField(fields[3], 1, 12, nil); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	// 7 is accepted for Sunday as in most cron implementations
+	if window.daysOfWeek, err = parseCronField(fields[4], 0, 7, weekdays); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	if window.daysOfWeek[7] {
+		window.daysOfWeek[0] = true
+	}
+	window.anyDayOfMonth = strings.HasPrefix(fields[2], "*")
+	window.anyDayOfWeek = strings.HasPrefix(fields[4], "*")
+	return window, nil
+}
+
+// parseCronField parses a comma separated list of "*", values and ranges, each optionally
+// followed by a /step, into a lookup table indexed by value
+func parseCronField(field string, minValue, maxValue int, names map[string]time.Weekday) ([]bool, error) {
+	matches := make([]bool, maxValue+1)
+	for _, part := range strings.Split(strings.ToLower(field), ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		first, last := minValue, maxValue
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if first, err = parseCronValue(from, minValue, maxValue, names); err != nil {
+				return nil, err
+			}
+			last = first
+			if isRange {
+				if last, err = parseCronValue(to, minValue, maxValue, names); err != nil {
+					return nil, err
+				}
+			} else if hasStep {
+				last = maxValue
+			}
+			if last < first {
+				return nil, fmt.Errorf("invalid range %q", rangePart)
+			}
+		}
+
+		for value := first; value <= last; value += step {
+			matches[value] = true
+		}
+	}
+	return matches, nil
+}
+
+func parseCronValue(value string, minValue, maxValue int, names map[string]time.Weekday) (int, error) {
+	if day, ok := names[value]; ok {
+		return int(day), nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < minValue || n > maxValue {
+		return 0, fmt.Errorf("value %q out of range %d-%d", value, minValue, maxValue)
+	}
+	return n, nil
+}
@@ -0,0 +1,89 @@
+package services_test
+
+import (
+	"time"
+
+	"go-template-microservice/internal/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SendingSchedule", func() {
+	istanbul, _ := time.LoadLocation("Europe/Istanbul")
+	// 2026-10-16 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, istanbul)
+	}
+
+	Describe("ParseSendingSchedule", func() {
+		It("should reject malformed windows", func() {
+			for _, spec := range []string{
+				"mon-fri",
+				"someday 09:00-21:00",
+				"mon 9-21",
+				"mon 24:00-08:00",
+				"mon 09:00-09:00",
+				"60 * * * *",
+				"* 20-8 * * *",
+				"* * * * */0",
+			} {
+				_, err := services.ParseSendingSchedule([]string{spec}, "UTC")
+				Expect(err).To(HaveOccurred(), spec)
+			}
+		})
+
+		It("should reject an unknown timezone", func() {
+			_, err := services.ParseSendingSchedule(nil, "Mars/Olympus")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("IsOpen", func() {
+		It("should always be open without windows", func() {
+			var schedule *services.SendingSchedule
+			Expect(schedule.IsOpen(time.Now())).To(BeTrue())
+
+			schedule, err := services.ParseSendingSchedule([]string{""}, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(schedule.IsOpen(time.Now())).To(BeTrue())
+		})
+
+		It("should match days of the week with a time range in the schedule's timezone", func() {
+			schedule, err := services.ParseSendingSchedule([]string{"mon-fri 09:00-21:00", "sat 10:00-18:00"}, "Europe/Istanbul")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(schedule.IsOpen(at(16, 9, 0))).To(BeTrue())
+			Expect(schedule.IsOpen(at(16, 20, 59))).To(BeTrue())
+			Expect(schedule.IsOpen(at(16, 21, 0))).To(BeFalse())
+			Expect(schedule.IsOpen(at(16, 8, 59))).To(BeFalse())
+			Expect(schedule.IsOpen(at(17, 9, 30))).To(BeFalse())
+			Expect(schedule.IsOpen(at(17, 12, 0))).To(BeTrue())
+			Expect(schedule.IsOpen(at(18, 12, 0))).To(BeFalse())
+			// 06:30 UTC is 09:30 in Istanbul
+			Expect(schedule.IsOpen(time.Date(2026, time.October, 16, 6, 30, 0, 0, time.UTC))).To(BeTrue())
+		})
+
+		It("should attribute overnight ranges to the day they start on", func() {
+			schedule, err := services.ParseSendingSchedule([]string{"fri 22:00-02:00"}, "Europe/Istanbul")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(schedule.IsOpen(at(16, 23, 0))).To(BeTrue())
+			Expect(schedule.IsOpen(at(17, 1, 59))).To(BeTrue())
+			Expect(schedule.IsOpen(at(17, 2, 0))).To(BeFalse())
+			Expect(schedule.IsOpen(at(16, 1, 0))).To(BeFalse())
+		})
+
+		It("should match the minutes of a cron expression", func() {
+			schedule, err := services.ParseSendingSchedule([]string{"* 9-20 * * mon-fri", "0-29 10 * * 6,7"}, "Europe/Istanbul")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(schedule.IsOpen(at(16, 9, 0))).To(BeTrue())
+			Expect(schedule.IsOpen(at(16, 20, 59))).To(BeTrue())
+			Expect(schedule.IsOpen(at(16, 21, 0))).To(BeFalse())
+			Expect(schedule.IsOpen(at(17, 10, 15))).To(BeTrue())
+			Expect(schedule.IsOpen(at(17, 10, 30))).To(BeFalse())
+			Expect(schedule.IsOpen(at(18, 10, 0))).To(BeTrue())
+		})
+	})
+})
@@ -46,6 +46,7 @@ var (
 	messageSenderMock      *serviceMocks.MockMessageSenderService
 	messageRepoMock        *repoMocks.MockMessageRepository
 	messageCacheMock       *repoMocks.MockMessageCacheRepository
+	schedulerStateMock     *repoMocks.MockSchedulerStateRepository
 )
 
 var (
@@ -60,7 +61,7 @@ var _ = BeforeSuite(func() {
 	testDBPath = filepath.Join(os.TempDir(), "test_services_message.db")
 	os.Remove(testDBPath)
 
-	sqliteInst, err = sqlite.NewSqliteInstanceWithSchemas(testDBPath, []string{models.GetMessageSchema()})
+	sqliteInst, err = sqlite.NewSqliteInstanceWithSchemas(testDBPath, []sqlite.Schema{models.GetMessageSchema()})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(sqliteInst).NotTo(BeNil())
 
@@ -80,6 +81,7 @@ var _ = BeforeEach(func() {
 	messageSenderMock = serviceMocks.NewMockMessageSenderService(mockCtrl)
 	messageRepoMock = repoMocks.NewMockMessageRepository(mockCtrl)
 	messageCacheMock = repoMocks.NewMockMessageCacheRepository(mockCtrl)
+	schedulerStateMock = repoMocks.NewMockSchedulerStateRepository(mockCtrl)
 })
 
 var _ = AfterSuite(func() {
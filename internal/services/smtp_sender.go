@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-template-microservice/internal/resources/response"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SMTPSenderOptions configures the email to SMS gateway, messages are mailed to
+// <recipient without the leading +>@<Domain>
+type SMTPSenderOptions struct {
+	// Addr is the host:port of the SMTP server
+	Addr     string
+	Username string
+	Password string
+	From     string
+	Domain   string
+}
+
+// smtpSender delivers messages through an SMTP to SMS gateway. STARTTLS is used whenever the
+// server offers it and credentials are only sent when a username is configured.
+type smtpSender struct {
+	opts   SMTPSenderOptions
+	dialer *net.Dialer
+	logger *logrus.Logger
+}
+
+func NewSMTPSender(opts SMTPSenderOptions, logger *logrus.Logger) MessageSenderService {
+	return &smtpSender{
+		opts:   opts,
+		dialer: &net.Dialer{Timeout: SendTimeout},
+		logger: logger,
+	}
+}
+
+func (s *smtpSender) Send(ctx context.Context, to, content string) (*response.WebhookResponse, error) {
+	messageID, err := newSMTPMessageID()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.deliver(ctx, strings.TrimPrefix(to, "+")+"@"+s.opts.Domain, messageID, content); err != nil {
+		s.logger.WithError(err).Error("Failed to send message through SMTP")
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, classifySMTPError(err)
+	}
+
+	return &response.WebhookResponse{Message: "Accepted", MessageID: messageID, Endpoint: s.opts.Addr}, nil
+}
+
+func (s *smtpSender) deliver(ctx context.Context, rcpt, messageID, content string) error {
+	conn, err := s.dialer.DialContext(ctx, "tcp", s.opts.Addr)
+	if err != nil {
+		return err
+	}
+	// the smtp package has no context support, the deadline bounds the whole conversation
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(s.dialer.Timeout)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	host, _, _ := net.SplitHostPort(s.opts.Addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.opts.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.opts.Username, s.opts.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(s.opts.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(rcpt); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	msg := "From: " + s.opts.From + "\r\n" +
+		"To: " + rcpt + "\r\n" +
+		"Message-ID: <" + messageID + "@" + s.opts.Domain + ">\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + content + "\r\n"
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	// the server accepted the message with the DATA reply, failing to end the session must not
+	// get it sent again
+	if err := client.Quit(); err != nil {
+		s.logger.WithError(err).WithField("messageID", messageID).Warn("SMTP server accepted the message but QUIT failed")
+	}
+	return nil
+}
+
+// classifySMTPError maps 5xx replies to permanent errors, everything else may succeed on retry
+func classifySMTPError(err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return &PermanentError{StatusCode: protoErr.Code, Err: err}
+	}
+	if protoErr != nil {
+		return &TransientError{StatusCode: protoErr.Code, Err: err}
+	}
+	return &TransientError{Err: err}
+}
+
+func newSMTPMessageID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate message id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,170 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"strings"
+
+	"go-template-microservice/internal/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeSMTPServer accepts a single SMTP conversation, replying rcptReply to RCPT TO and
+// quitReply to QUIT, and records the recipient and the DATA section
+type fakeSMTPServer struct {
+	listener  net.Listener
+	rcptReply string
+	quitReply string
+	rcpt      chan string
+	data      chan string
+}
+
+func newFakeSMTPServer(rcptReply string) *fakeSMTPServer {
+	return newQuittingFakeSMTPServer(rcptReply, "221 bye")
+}
+
+func newQuittingFakeSMTPServer(rcptReply, quitReply string) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+
+	server := &fakeSMTPServer{
+		listener:  listener,
+		rcptReply: rcptReply,
+		quitReply: quitReply,
+		rcpt:      make(chan string, 1),
+		data:      make(chan string, 1),
+	}
+	go server.serve()
+	return server
+}
+
+func (s *fakeSMTPServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	_ = text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			_ = text.PrintfLine("250 localhost")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			s.rcpt <- strings.Trim(line[len("RCPT TO:"):], "<>")
+			_ = text.PrintfLine(s.rcptReply)
+		case command == "DATA":
+			_ = text.PrintfLine("354 go ahead")
+			data, err := text.ReadDotLines()
+			if err != nil {
+				return
+			}
+			s.data <- strings.Join(data, "\n")
+			_ = text.PrintfLine("250 queued")
+		case command == "QUIT":
+			_ = text.PrintfLine(s.quitReply)
+			return
+		default:
+			_ = text.PrintfLine("250 ok")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) Close() {
+	s.listener.Close()
+}
+
+var _ = Describe("SMTPSender", func() {
+	Describe("Send", func() {
+		It("should mail the content to the gateway address of the recipient", func() {
+			server := newFakeSMTPServer("250 ok")
+			defer server.Close()
+
+			sender := services.NewSMTPSender(services.SMTPSenderOptions{
+				Addr:   server.listener.Addr().String(),
+				From:   "sms@example.com",
+				Domain: "sms.example.com",
+			}, logger)
+			resp, err := sender.Send(context.Background(), "+905551234567", "Hello World")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.MessageID).To(HaveLen(32))
+			Expect(<-server.rcpt).To(Equal("905551234567@sms.example.com"))
+
+			data := <-server.data
+			Expect(data).To(ContainSubstring("Message-ID: <" + resp.MessageID + "@sms.example.com>"))
+			Expect(data).To(HaveSuffix("\nHello World"))
+		})
+
+		It("should treat the message as sent when the gateway accepted it but QUIT fails", func() {
+			server := newQuittingFakeSMTPServer("250 ok", "421 closing connection")
+			defer server.Close()
+
+			sender := services.NewSMTPSender(services.SMTPSenderOptions{
+				Addr:   server.listener.Addr().String(),
+				From:   "sms@example.com",
+				Domain: "sms.example.com",
+			}, logger)
+			resp, err := sender.Send(context.Background(), "+905551234567", "Hello World")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.MessageID).To(HaveLen(32))
+			Expect(<-server.data).To(HaveSuffix("\nHello World"))
+		})
+
+		It("should return a permanent error when the gateway rejects the recipient", func() {
+			server := newFakeSMTPServer("550 no such number")
+			defer server.Close()
+
+			sender := services.NewSMTPSender(services.SMTPSenderOptions{
+				Addr:   server.listener.Addr().String(),
+				From:   "sms@example.com",
+				Domain: "sms.example.com",
+			}, logger)
+			resp, err := sender.Send(context.Background(), "+905551234567", "Hello World")
+
+			Expect(resp).To(BeNil())
+			var permanent *services.PermanentError
+			Expect(errors.As(err, &permanent)).To(BeTrue())
+			Expect(permanent.StatusCode).To(Equal(550))
+		})
+
+		It("should return a transient error when the gateway asks to retry later", func() {
+			server := newFakeSMTPServer("451 try again later")
+			defer server.Close()
+
+			sender := services.NewSMTPSender(services.SMTPSenderOptions{
+				Addr:   server.listener.Addr().String(),
+				From:   "sms@example.com",
+				Domain: "sms.example.com",
+			}, logger)
+			_, err := sender.Send(context.Background(), "+905551234567", "Hello World")
+
+			var transient *services.TransientError
+			Expect(errors.As(err, &transient)).To(BeTrue())
+			Expect(transient.StatusCode).To(Equal(451))
+		})
+
+		It("should return a transient error when the gateway is unreachable", func() {
+			server := newFakeSMTPServer("250 ok")
+			addr := server.listener.Addr().String()
+			server.Close()
+
+			sender := services.NewSMTPSender(services.SMTPSenderOptions{Addr: addr, Domain: "sms.example.com"}, logger)
+			_, err := sender.Send(context.Background(), "+905551234567", "Hello World")
+
+			var transient *services.TransientError
+			Expect(errors.As(err, &transient)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultAuthHeader is the header the static key is sent in when none is configured
+const DefaultAuthHeader = "x-ins-auth-key"
+
+// WebhookAuth authenticates outbound webhook requests
+type WebhookAuth interface {
+	// Apply adds the credentials to req, failing when they can't be obtained
+	Apply(ctx context.Context, req *http.Request) error
+}
+
+// revocableAuth is implemented by strategies whose credentials the webhook may stop accepting
+// before they expire, e.g. a revoked bearer token
+type revocableAuth interface {
+	// revoke drops the credentials applied to req so the next request obtains new ones
+	revoke(req *http.Request)
+}
+
+type staticHeaderAuth struct {
+	header string
+	value  string
+}
+
+// NewStaticHeaderAuth sends value in header, an empty header uses DefaultAuthHeader
+func NewStaticHeaderAuth(header, value string) WebhookAuth {
+	if header == "" {
+		header = DefaultAuthHeader
+	}
+	return &staticHeaderAuth{header: header, value: value}
+}
+
+func (a *staticHeaderAuth) Apply(_ context.Context, req *http.Request) error {
+	req.Header.Set(a.header, a.value)
+	return nil
+}
+
+type basicAuth struct {
+	username string
+	password string
+}
+
+// NewBasicAuth sends the credentials in an HTTP Basic Authorization header
+func NewBasicAuth(username, password string) WebhookAuth {
+	return &basicAuth{username: username, password: password}
+}
+
+func (a *basicAuth) Apply(_ context.Context, req *http.Request) error {
+	req.SetBasicAuth(a.username, a.password)
+	return nil
+}
+
+type OAuth2Options struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// RefreshBefore renews the token this long before it expires so no request goes out with a
+	// token that expires in flight
+	RefreshBefore time.Duration
+}
+
+// oauth2ClientCredentials fetches bearer tokens with the client credentials grant and caches
+// them until they are about to expire
+type oauth2ClientCredentials struct {
+	opts   OAuth2Options
+	client *http.Client
+	logger *logrus.Logger
+
+	// mu also serializes the token requests so concurrent sends share a single refresh
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewOAuth2ClientCredentials(opts OAuth2Options, logger *logrus.Logger) WebhookAuth {
+	return &oauth2ClientCredentials{
+		opts: opts,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		logger: logger,
+	}
+}
+
+type oauth2TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (a *oauth2ClientCredentials) Apply(ctx context.Context, req *http.Request) error {
+	token, err := a.currentToken(ctx)
+	if err != nil {
+		// the message is not at fault, it is retried once the token endpoint recovers
+		if ctx.Err() != nil {
+			return err
+		}
+		return &TransientError{Err: err}
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// revoke drops the cached token when req carried it, a token refreshed in the meantime is kept
+func (a *oauth2ClientCredentials) revoke(req *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && req.Header.Get("Authorization") == "Bearer "+a.token {
+		a.logger.Warn("Webhook rejected the OAuth2 token, dropping it")
+		a.token = ""
+		a.expiresAt = time.Time{}
+	}
+}
+
+func (a *oauth2ClientCredentials) currentToken(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Now().Before(a.expiresAt.Add(-a.opts.RefreshBefore)) {
+		return a.token, nil
+	}
+
+	token, expiresIn, err := a.fetchToken(ctx)
+	if err != nil {
+		a.logger.WithError(err).Error("Failed to fetch OAuth2 token")
+		return "", err
+	}
+	a.token = token
+	// tokens without an expiry are refreshed as if they lived just past RefreshBefore
+	if expiresIn <= 0 {
+		expiresIn = a.opts.RefreshBefore + time.Minute
+	}
+	a.expiresAt = time.Now().Add(expiresIn)
+	a.logger.WithField("expiresAt", a.expiresAt).Debug("Fetched OAuth2 token")
+	return a.token, nil
+}
+
+func (a *oauth2ClientCredentials) fetchToken(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.opts.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.opts.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", a.opts.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(a.opts.ClientID), url.QueryEscape(a.opts.ClientSecret))
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token endpoint returned status code: %d", resp.StatusCode)
+	}
+
+	var tokenResp oauth2TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", 0, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", 0, errors.New("token response has no access_token")
+	}
+	if tokenResp.TokenType != "" && !strings.EqualFold(tokenResp.TokenType, "bearer") {
+		return "", 0, fmt.Errorf("unsupported token type %q", tokenResp.TokenType)
+	}
+	return tokenResp.AccessToken, time.Duration(tokenResp.ExpiresIn) * time.Second, nil
+}
@@ -0,0 +1,168 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"go-template-microservice/internal/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WebhookAuth", func() {
+	var (
+		received *http.Request
+		// revokedToken is answered with 401 by the webhook
+		revokedToken string
+		webhook      *httptest.Server
+	)
+
+	BeforeEach(func() {
+		received = nil
+		revokedToken = ""
+		webhook = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			if revokedToken != "" && r.Header.Get("Authorization") == "Bearer "+revokedToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-auth"}`))
+		}))
+	})
+
+	AfterEach(func() {
+		webhook.Close()
+	})
+
+	send := func(auth services.WebhookAuth) error {
+		sender := services.NewMessageSenderService(webhook.URL, "unused", logger, services.WithAuth(auth))
+		_, err := sender.Send(context.Background(), "+905551234567", "Hello World")
+		return err
+	}
+
+	Describe("static header", func() {
+		It("should send the key in x-ins-auth-key by default", func() {
+			sender := services.NewMessageSenderService(webhook.URL, "test-auth-key", logger)
+			_, err := sender.Send(context.Background(), "+905551234567", "Hello World")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(received.Header.Get("x-ins-auth-key")).To(Equal("test-auth-key"))
+		})
+
+		It("should send the key in the configured header", func() {
+			Expect(send(services.NewStaticHeaderAuth("X-Api-Key", "secret-key"))).To(Succeed())
+
+			Expect(received.Header.Get("X-Api-Key")).To(Equal("secret-key"))
+			Expect(received.Header.Get("x-ins-auth-key")).To(BeEmpty())
+		})
+	})
+
+	Describe("basic auth", func() {
+		It("should send the credentials in the Authorization header", func() {
+			Expect(send(services.NewBasicAuth("gateway-user", "gateway-pass"))).To(Succeed())
+
+			username, password, ok := received.BasicAuth()
+			Expect(ok).To(BeTrue())
+			Expect(username).To(Equal("gateway-user"))
+			Expect(password).To(Equal("gateway-pass"))
+		})
+	})
+
+	Describe("OAuth2 client credentials", func() {
+		var (
+			tokenRequests atomic.Int32
+			expiresIn     int
+			tokenStatus   int
+			tokenServer   *httptest.Server
+		)
+
+		BeforeEach(func() {
+			tokenRequests.Store(0)
+			expiresIn = 3600
+			tokenStatus = http.StatusOK
+			tokenServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := tokenRequests.Add(1)
+				clientID, clientSecret, _ := r.BasicAuth()
+				_ = r.ParseForm()
+				if clientID != "client" || clientSecret != "secret" || r.PostForm.Get("grant_type") != "client_credentials" || r.PostForm.Get("scope") != "sms.send sms.read" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(tokenStatus)
+				_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, n, expiresIn)
+			}))
+		})
+
+		AfterEach(func() {
+			tokenServer.Close()
+		})
+
+		newAuth := func(refreshBefore time.Duration) services.WebhookAuth {
+			return services.NewOAuth2ClientCredentials(services.OAuth2Options{
+				TokenURL:      tokenServer.URL,
+				ClientID:      "client",
+				ClientSecret:  "secret",
+				Scopes:        []string{"sms.send", "sms.read"},
+				RefreshBefore: refreshBefore,
+			}, logger)
+		}
+
+		It("should send a bearer token and reuse it until it is about to expire", func() {
+			auth := newAuth(time.Minute)
+
+			for i := 0; i < 3; i++ {
+				Expect(send(auth)).To(Succeed())
+				Expect(received.Header.Get("Authorization")).To(Equal("Bearer token-1"))
+			}
+			Expect(tokenRequests.Load()).To(BeEquivalentTo(1))
+		})
+
+		It("should refresh the token before it expires", func() {
+			expiresIn = 30
+			auth := newAuth(time.Minute)
+
+			Expect(send(auth)).To(Succeed())
+			Expect(received.Header.Get("Authorization")).To(Equal("Bearer token-1"))
+			Expect(send(auth)).To(Succeed())
+			Expect(received.Header.Get("Authorization")).To(Equal("Bearer token-2"))
+		})
+
+		It("should drop a token the webhook stops accepting and retry the message with a new one", func() {
+			auth := newAuth(time.Minute)
+			Expect(send(auth)).To(Succeed())
+			Expect(received.Header.Get("Authorization")).To(Equal("Bearer token-1"))
+
+			revokedToken = "token-1"
+			err := send(auth)
+			var transient *services.TransientError
+			Expect(errors.As(err, &transient)).To(BeTrue())
+			Expect(transient.StatusCode).To(Equal(http.StatusUnauthorized))
+
+			Expect(send(auth)).To(Succeed())
+			Expect(received.Header.Get("Authorization")).To(Equal("Bearer token-2"))
+			Expect(tokenRequests.Load()).To(BeEquivalentTo(2))
+		})
+
+		It("should not call the webhook and return a transient error when no token can be fetched", func() {
+			tokenStatus = http.StatusServiceUnavailable
+			auth := newAuth(time.Minute)
+
+			err := send(auth)
+			var transient *services.TransientError
+			Expect(errors.As(err, &transient)).To(BeTrue())
+			Expect(received).To(BeNil())
+
+			// a failed fetch isn't cached
+			tokenStatus = http.StatusOK
+			Expect(send(auth)).To(Succeed())
+			Expect(received.Header.Get("Authorization")).To(Equal("Bearer token-2"))
+		})
+	})
+})
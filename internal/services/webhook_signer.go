@@ -0,0 +1,64 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSignatureHeader is the header carrying the signature when none is configured
+const DefaultSignatureHeader = "X-Webhook-Signature"
+
+// WebhookSigner signs outbound webhook bodies with HMAC-SHA256. The signature header looks like
+// "t=1700000000,v1=<hex>" where v1 is the HMAC of "<t>.<body>", receivers recompute it and reject
+// stale timestamps to stop replays. During a secret rotation every active secret adds its own v1
+// entry so receivers accept the request with either the old or the new secret.
+type WebhookSigner struct {
+	header  string
+	secrets [][]byte
+}
+
+// NewWebhookSigner signs with every non-empty secret, an empty header uses DefaultSignatureHeader
+func NewWebhookSigner(header string, secrets []string) (*WebhookSigner, error) {
+	if header == "" {
+		header = DefaultSignatureHeader
+	}
+
+	signer := &WebhookSigner{header: header}
+	for _, secret := range secrets {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			signer.secrets = append(signer.secrets, []byte(secret))
+		}
+	}
+	if len(signer.secrets) == 0 {
+		return nil, errors.New("webhook signing requires at least one secret")
+	}
+	return signer, nil
+}
+
+// Header returns the name of the signature header
+func (s *WebhookSigner) Header() string {
+	return s.header
+}
+
+// Sign returns the signature header value of body sent at the given time
+func (s *WebhookSigner) Sign(body []byte, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+
+	var b strings.Builder
+	b.WriteString("t=")
+	b.WriteString(timestamp)
+	for _, secret := range s.secrets {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(timestamp))
+		mac.Write([]byte("."))
+		mac.Write(body)
+		b.WriteString(",v1=")
+		b.WriteString(hex.EncodeToString(mac.Sum(nil)))
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package services_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-template-microservice/internal/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// expectedSignature computes the v1 entry a receiver holding secret expects
+func expectedSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+var _ = Describe("WebhookSigner", func() {
+	Describe("NewWebhookSigner", func() {
+		It("should require at least one secret", func() {
+			_, err := services.NewWebhookSigner("", []string{"", " "})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should default the header name", func() {
+			signer, err := services.NewWebhookSigner("", []string{"secret"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signer.Header()).To(Equal(services.DefaultSignatureHeader))
+		})
+	})
+
+	Describe("Sign", func() {
+		It("should sign the timestamp and the body with every active secret", func() {
+			signer, err := services.NewWebhookSigner("X-Signature", []string{"old-secret", "new-secret"})
+			Expect(err).NotTo(HaveOccurred())
+
+			body := []byte(`{"content":"Hello","to":"+905551234567"}`)
+			at := time.Unix(1700000000, 0)
+
+			Expect(signer.Sign(body, at)).To(Equal("t=1700000000" +
+				",v1=" + expectedSignature("old-secret", "1700000000", body) +
+				",v1=" + expectedSignature("new-secret", "1700000000", body)))
+		})
+
+		It("should change with the timestamp so old signatures can't be replayed", func() {
+			signer, err := services.NewWebhookSigner("", []string{"secret"})
+			Expect(err).NotTo(HaveOccurred())
+
+			body := []byte("payload")
+			Expect(signer.Sign(body, time.Unix(1700000000, 0))).NotTo(Equal(signer.Sign(body, time.Unix(1700000300, 0))))
+		})
+	})
+
+	Describe("signed webhook requests", func() {
+		It("should send a signature the receiver can verify against the raw body", func() {
+			var header string
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Get("X-Signature")
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-signed"}`))
+			}))
+			defer server.Close()
+
+			signer, err := services.NewWebhookSigner("X-Signature", []string{"secret"})
+			Expect(err).NotTo(HaveOccurred())
+			sender := services.NewMessageSenderService(server.URL, "test-auth-key", logger, services.WithSigner(signer))
+
+			_, err = sender.Send(context.Background(), "+905551234567", "Hello World")
+			Expect(err).NotTo(HaveOccurred())
+
+			parts := strings.Split(header, ",")
+			Expect(parts).To(HaveLen(2))
+			timestamp := strings.TrimPrefix(parts[0], "t=")
+			unix, err := strconv.ParseInt(timestamp, 10, 64)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(time.Unix(unix, 0)).To(BeTemporally("~", time.Now(), 5*time.Second))
+			Expect(parts[1]).To(Equal("v1=" + expectedSignature("secret", timestamp, body)))
+		})
+	})
+})
@@ -12,7 +12,23 @@ import (
 type ISqliteInstance interface {
 	Database() *sql.DB
 	Close() error
-	InitTables(schemas []string) error
+	InitTables(schemas []Schema) error
+}
+
+// Schema describes a table. Columns added after the table was first released are listed in
+// Columns so databases created before them are upgraded, CREATE TABLE IF NOT EXISTS leaves an
+// existing table as it is. Indexes run last since they may use the added columns.
+type Schema struct {
+	Table   string
+	Columns []Column
+	Indexes string
+}
+
+// Column is added to Table with ALTER TABLE when the table doesn't have it yet
+type Column struct {
+	Table      string
+	Name       string
+	Definition string
 }
 
 type sqliteInstance struct {
@@ -28,7 +44,7 @@ func NewSqliteInstance(dbName string) (ISqliteInstance, error) {
 	return instance, nil
 }
 
-func NewSqliteInstanceWithSchemas(dbName string, schemas []string) (ISqliteInstance, error) {
+func NewSqliteInstanceWithSchemas(dbName string, schemas []Schema) (ISqliteInstance, error) {
 	instance := &sqliteInstance{}
 	if err := instance.initDB(dbName); err != nil {
 		log.Fatal("Failed to initialize database", err)
@@ -66,16 +82,71 @@ func (s *sqliteInstance) initDB(dbName string) error {
 	return nil
 }
 
-func (s *sqliteInstance) InitTables(schemas []string) error {
+func (s *sqliteInstance) InitTables(schemas []Schema) error {
 	if s.db == nil {
 		return fmt.Errorf("database not initialized")
 	}
 
 	for _, schema := range schemas {
-		if _, err := s.db.Exec(schema); err != nil {
+		if _, err := s.db.Exec(schema.Table); err != nil {
 			return fmt.Errorf("failed to execute schema: %v", err)
 		}
+		if err := s.addColumns(schema.Columns); err != nil {
+			return err
+		}
+		if schema.Indexes == "" {
+			continue
+		}
+		if _, err := s.db.Exec(schema.Indexes); err != nil {
+			return fmt.Errorf("failed to create indexes: %v", err)
+		}
 	}
 
 	return nil
 }
+
+// addColumns adds the columns their table is missing, it is a no-op on an up to date database
+func (s *sqliteInstance) addColumns(columns []Column) error {
+	existing := make(map[string]map[string]bool)
+	for _, column := range columns {
+		if _, ok := existing[column.Table]; !ok {
+			names, err := s.columnNames(column.Table)
+			if err != nil {
+				return err
+			}
+			existing[column.Table] = names
+		}
+		if existing[column.Table][column.Name] {
+			continue
+		}
+
+		query := fmt.Sprintf(`ALTER TABLE %q ADD COLUMN %q %s`, column.Table, column.Name, column.Definition)
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %v", column.Table, column.Name, err)
+		}
+		existing[column.Table][column.Name] = true
+	}
+	return nil
+}
+
+func (s *sqliteInstance) columnNames(table string) (map[string]bool, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`PRAGMA table_info(%q)`, table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	names := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %v", table, err)
+		}
+		names[name] = true
+	}
+	return names, rows.Err()
+}
@@ -6,10 +6,13 @@ const (
 	ValidationErrCode = "validation_failed"
 	UnexpectedErrCode = "unexpected_error"
 	BodyParserErrCode = "body_parser_failed"
+	NotFoundErrCode   = "not_found"
+	ConflictErrCode   = "conflict"
 
 	UnexpectedMsg = "An unexpected error has occurred."
 	ValidationMsg = "The given data was invalid."
 	BodyParserMsg = "The given values could not be parsed."
+	NotFoundMsg   = "The requested resource could not be found."
 )
 
 type Error struct {